@@ -18,11 +18,36 @@ func Parse(configuration config.ProgramConfig) ([]string, config.CLI) {
 
 	IsSelectMode := flag.Bool("select", false, "Interactive selection of the required application version")
 	ShowConfig := flag.String("show-config", "", "Show the configuration for the specified command")
+	ShowRaw := flag.Bool("raw", false, "Show the configuration with ${...} variables unexpanded (with --show-config)")
+	DryRun := flag.Bool("dry-run", false, "Print the container that would be run without pulling, building or creating anything")
+	ShowSecrets := flag.Bool("show-secrets", false, "Print the values of variables taken from the host or env files (with --dry-run)")
+	Verbose := flag.Bool("verbose", false, "Print the matched program and hooks before running")
+	Profile := flag.String("profile", "", "Overlay the settings of a profile (defaults to $CUBX_PROFILE or default_profile)")
+	Output := flag.String("output", "yaml", "Output format of --dry-run: yaml or json")
 	FileIgnores := FlagArray("ignore-path", "Files or dirs to ignore (can be specified multiple times)")
+	Expose := FlagArray("expose", "Expose only these files, dirs or globs of the working directory, or git-tracked (can be specified multiple times)")
 	Session := flag.Bool("session", false, "Start a session in which all programs are available directly")
+	Env := FlagArray("env", "Set an environment variable KEY=VAL in the container (can be specified multiple times)")
+	EnvFiles := FlagArray("env-file", "Read environment variables from a file (can be specified multiple times)")
+	Ports := FlagArray("publish", "Publish a container port to the host as host:container/proto (can be specified multiple times)")
 
 	flag.Parse()
 	commandArgs := flag.Args()
 
-	return commandArgs, config.CLI{IsSelectMode: *IsSelectMode, FileIgnores: *FileIgnores, ShowConfig: *ShowConfig, Session: *Session}
+	return commandArgs, config.CLI{
+		IsSelectMode: *IsSelectMode,
+		FileIgnores:  *FileIgnores,
+		Expose:       *Expose,
+		ShowConfig:   *ShowConfig,
+		ShowRaw:      *ShowRaw,
+		DryRun:       *DryRun,
+		ShowSecrets:  *ShowSecrets,
+		Output:       *Output,
+		Verbose:      *Verbose,
+		Profile:      *Profile,
+		Session:      *Session,
+		Env:          *Env,
+		EnvFiles:     *EnvFiles,
+		Ports:        *Ports,
+	}
 }
@@ -9,6 +9,18 @@ import (
 	"strings"
 )
 
+var configCommands = []struct {
+	Command     string
+	Description string
+}{
+	{"config validate [file...]", "Check config files without running anything"},
+	{"config schema", "Print the JSON Schema of the config format"},
+	{"config explain <program>", "Show where each value of a program comes from"},
+	{"cache ls [program]", "List the cache volumes of programs"},
+	{"cache du [program]", "Show the disk usage of the cache volumes"},
+	{"cache prune [program]", "Remove the cache volumes"},
+}
+
 func getHelpMessage(configuration config.ProgramConfig) string {
 	header := "cubx - Isolated App Launch Made Easy"
 	description := "A program to launch applications in isolated Docker containers"
@@ -35,6 +47,13 @@ func getHelpMessage(configuration config.ProgramConfig) string {
 
 	sb.WriteString("\n")
 
+	sb.WriteString(fmt.Sprintf("%sConfig and cache%s\n", tui.ColorPurple, tui.ColorReset))
+	sb.WriteString("\n")
+	for _, c := range configCommands {
+		sb.WriteString(fmt.Sprintf("%s%-30s%s - %s%s%s\n", tui.ColorGreen, c.Command, tui.ColorReset, tui.ColorYellow, c.Description, tui.ColorReset))
+	}
+	sb.WriteString("\n")
+
 	sb.WriteString(fmt.Sprintf("%sCommands%s\n", tui.ColorPurple, tui.ColorReset))
 
 	categories := make(map[string][]config.Program)
@@ -0,0 +1,101 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/eddort/cubx/internal/config"
+	"github.com/eddort/cubx/internal/docker"
+	"github.com/eddort/cubx/internal/tui"
+
+	"github.com/docker/go-units"
+)
+
+// CacheCommand handles the `cubx cache <subcommand> [program]` family of
+// commands that manage the cache volumes of programs.
+type CacheCommand struct {
+	Flags         config.CLI
+	Configuration *config.ProgramConfig
+	Args          []string
+}
+
+func (c *CacheCommand) Execute() error {
+	if len(c.Args) == 0 {
+		return fmt.Errorf("missing cache subcommand, expected one of: ls, du, prune")
+	}
+	if len(c.Args) > 2 {
+		return fmt.Errorf("usage: cubx cache %s [program]", c.Args[0])
+	}
+
+	program := ""
+	if len(c.Args) == 2 {
+		program = c.Args[1]
+	}
+
+	switch c.Args[0] {
+	case "ls":
+		return c.list(program, false)
+	case "du":
+		return c.list(program, true)
+	case "prune":
+		return c.prune(program)
+	}
+	return fmt.Errorf("unknown cache subcommand: %s", c.Args[0])
+}
+
+// list prints the cache volumes, with their disk usage when withSize is set.
+func (c *CacheCommand) list(program string, withSize bool) error {
+	caches, err := docker.ListCaches(program, withSize)
+	if err != nil {
+		return err
+	}
+	if len(caches) == 0 {
+		fmt.Println("no caches found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if withSize {
+		fmt.Fprintln(w, "PROGRAM\tPATH\tSCOPE\tSIZE\tVOLUME")
+	} else {
+		fmt.Fprintln(w, "PROGRAM\tPATH\tSCOPE\tVOLUME")
+	}
+
+	var total int64
+	for _, cache := range caches {
+		scope := cache.Scope
+		if cache.Project != "" {
+			scope += " " + cache.Project
+		}
+		if withSize {
+			size := "unknown"
+			if cache.Size >= 0 {
+				size = units.HumanSize(float64(cache.Size))
+				total += cache.Size
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", cache.Program, cache.Path, scope, size, cache.Name)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", cache.Program, cache.Path, scope, cache.Name)
+		}
+	}
+	if withSize {
+		fmt.Fprintf(w, "total\t\t\t%s\t\n", units.HumanSize(float64(total)))
+	}
+	return w.Flush()
+}
+
+// prune removes the cache volumes that are not used by a container.
+func (c *CacheCommand) prune(program string) error {
+	removed, err := docker.PruneCaches(program)
+	for _, cache := range removed {
+		fmt.Printf("%sremoved%s %s (%s %s)\n", tui.ColorGreen, tui.ColorReset, cache.Name, cache.Program, cache.Path)
+	}
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		fmt.Println("no caches found")
+	}
+	return nil
+}
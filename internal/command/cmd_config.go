@@ -0,0 +1,114 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/eddort/cubx/internal/config"
+	"github.com/eddort/cubx/internal/tui"
+)
+
+// ConfigCommand handles the `cubx config <subcommand>` family of commands.
+type ConfigCommand struct {
+	Flags         config.CLI
+	Configuration *config.ProgramConfig
+	Args          []string
+}
+
+func (c *ConfigCommand) Execute() error {
+	if len(c.Args) == 0 {
+		return fmt.Errorf("missing config subcommand, expected one of: validate, schema, explain")
+	}
+
+	switch c.Args[0] {
+	case "validate":
+		return c.validate(c.Args[1:])
+	case "schema":
+		return c.schema()
+	case "explain":
+		return c.explain(c.Args[1:])
+	}
+	return fmt.Errorf("unknown config subcommand: %s", c.Args[0])
+}
+
+// validate checks the given config files, or the ones cubx discovers from
+// the working directory, without running anything.
+func (c *ConfigCommand) validate(files []string) error {
+	if len(files) == 0 {
+		discovered, err := config.ConfigFiles()
+		if err != nil {
+			return err
+		}
+		files = discovered
+	}
+
+	if len(files) == 0 {
+		fmt.Println("no config files found")
+		return nil
+	}
+
+	invalid := 0
+	for _, file := range files {
+		err := config.ValidateFile(file)
+		if err == nil {
+			fmt.Printf("%s%s%s: ok\n", tui.ColorGreen, file, tui.ColorReset)
+			continue
+		}
+
+		invalid++
+		var validationErrors config.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, validationErr := range validationErrors {
+				fmt.Fprintln(os.Stderr, validationErr)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+		}
+	}
+
+	if invalid > 0 {
+		fmt.Fprintf(os.Stderr, "%s%d of %d config files are invalid%s\n", tui.ColorRed, invalid, len(files), tui.ColorReset)
+		return &ExitError{Code: 1}
+	}
+	return nil
+}
+
+// schema prints the JSON Schema of the config format.
+func (c *ConfigCommand) schema() error {
+	schema, err := config.JSONSchema()
+	if err != nil {
+		return fmt.Errorf("error generating the config schema: %w", err)
+	}
+	_, err = os.Stdout.Write(schema)
+	return err
+}
+
+// explain prints the effective values of a program with the config layer and
+// file position each of them came from, followed by the values they override.
+func (c *ConfigCommand) explain(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: cubx config explain <program>")
+	}
+
+	explanation, err := config.Explain(args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, value := range explanation.Values {
+		origin := "computed"
+		if value.Origin != nil {
+			origin = value.Origin.String()
+		}
+		fmt.Printf("%s%s%s: %s %s# %s%s\n", tui.ColorGreen, value.Path, tui.ColorReset, value.Value, tui.ColorBlue, origin, tui.ColorReset)
+		for _, overridden := range value.Overridden {
+			fmt.Printf("    %soverrides %s # %s%s\n", tui.ColorYellow, overridden.Value, overridden.Origin, tui.ColorReset)
+		}
+	}
+
+	for _, skipped := range explanation.Skipped {
+		fmt.Printf("%sskipped %s: %s # %s%s\n", tui.ColorPurple, skipped.Path, skipped.Reason, skipped.Origin, tui.ColorReset)
+	}
+	return nil
+}
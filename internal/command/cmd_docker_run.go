@@ -6,8 +6,10 @@ import (
 	"github.com/eddort/cubx/internal/docker"
 	"github.com/eddort/cubx/internal/registry"
 	"github.com/eddort/cubx/internal/tui"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/shlex"
 )
@@ -18,7 +20,20 @@ type DockerRunCommand struct {
 	CommandArgs   []string
 }
 
-func (s *DockerRunCommand) GetDockerMeta() (string, []string, *config.Settings, error) {
+// DockerMeta describes the container that is started for an invocation.
+type DockerMeta struct {
+	Image    string
+	Args     []string
+	Settings *config.Settings
+	// Program is the matched program or nil when the command is not configured
+	Program *config.Program
+	// Hooks are the hooks of the program matched by the arguments
+	Hooks []config.Hook
+	// Profile is the name of the active profile, if any
+	Profile string
+}
+
+func (s *DockerRunCommand) GetDockerMeta() (*DockerMeta, error) {
 	baseCommand := s.CommandArgs[0]
 	additionalArgs := s.CommandArgs[1:]
 
@@ -26,57 +41,148 @@ func (s *DockerRunCommand) GetDockerMeta() (string, []string, *config.Settings,
 
 	for _, programConfig := range s.Configuration.Programs {
 		if programConfig.Name == commandName {
-			if programConfig.Dockerfile != "" {
-				imageWithTag := programConfig.Image + ":" + programConfig.Tag
-				err := docker.BuildImage(programConfig.Dockerfile, imageWithTag, filepath.Dir(programConfig.Dockerfile))
-				if err != nil {
-					return "", nil, nil, fmt.Errorf("error while building docker image: %w", err)
-				}
+			hooks, err := programConfig.MatchHooks(additionalArgs)
+			if err != nil {
+				return nil, fmt.Errorf("error matching hooks: %w", err)
+			}
+			program, err := programConfig.WithHooks(hooks)
+			if err != nil {
+				return nil, err
 			}
+
 			// merge setting with flags
-			image, tag, args, err := handleProgram(dockerTag, commandName, additionalArgs, programConfig)
+			image, tag, args, err := handleProgram(dockerTag, commandName, additionalArgs, program)
 			if err != nil {
-				return "", nil, nil, fmt.Errorf("error handling program: %w", err)
+				return nil, fmt.Errorf("error handling program: %w", err)
 			}
 
-			settings, err := resolveProgramSettings(&s.Configuration.Settings, &programConfig.Settings, &programConfig.Hooks, additionalArgs)
+			if program.Dockerfile != "" && !s.Flags.DryRun {
+				err := docker.BuildImage(program.Dockerfile, image+":"+tag, filepath.Dir(program.Dockerfile))
+				if err != nil {
+					return nil, fmt.Errorf("error while building docker image: %w", err)
+				}
+			}
+
+			settings, err := withCaches(resolveProgramSettings(&s.Configuration.Settings, &program, hooks), &program)
 			if err != nil {
-				return "", nil, nil, fmt.Errorf("error resolving program settings: %w", err)
+				return nil, err
+			}
+			settings, profile, err := s.applyProfile(settings, program.Name)
+			if err != nil {
+				return nil, err
 			}
 			settingsWithFlags, err := mergeFlagsWithSettings(settings, s.Flags)
 			if err != nil {
-				return "", nil, nil, fmt.Errorf("error merging flags with settings: %w", err)
+				return nil, fmt.Errorf("error merging flags with settings: %w", err)
 			}
 
 			if s.Flags.IsSelectMode {
 				// TODO: move to the validation part
-				if programConfig.Dockerfile != "" {
-					return "", nil, nil, fmt.Errorf("use of the select flag is not allowed in local builds")
+				if program.Dockerfile != "" {
+					return nil, fmt.Errorf("use of the select flag is not allowed in local builds")
 				}
 				// TODO: add loader
 				tags, err := registry.FetchTags(image)
 				if err != nil {
-					return "", nil, nil, fmt.Errorf("error fetching tags: %w", err)
+					return nil, fmt.Errorf("error fetching tags: %w", err)
 				}
 				tag, err = tui.RunInteractivePrompt(tags, "latest")
 				if err != nil {
-					return "", nil, nil, fmt.Errorf("error processing tags: %w", err)
+					return nil, fmt.Errorf("error processing tags: %w", err)
 				}
 			}
 
-			return image + ":" + tag, args, settingsWithFlags, nil
+			return &DockerMeta{
+				Image:    image + ":" + tag,
+				Args:     args,
+				Settings: settingsWithFlags,
+				Program:  &program,
+				Hooks:    hooks,
+				Profile:  profile,
+			}, nil
 		}
 	}
 
-	return "ubuntu:" + dockerTag, s.CommandArgs, &s.Configuration.Settings, nil
+	settings, profile, err := s.applyProfile(&s.Configuration.Settings, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &DockerMeta{
+		Image:    "ubuntu:" + dockerTag,
+		Args:     s.CommandArgs,
+		Settings: settings,
+		Profile:  profile,
+	}, nil
+}
+
+// applyProfile overlays the settings of the active profile on the program
+// settings and returns them with the name of the profile.
+func (s *DockerRunCommand) applyProfile(settings *config.Settings, program string) (*config.Settings, string, error) {
+	name := s.Configuration.ActiveProfile(s.Flags.Profile)
+	if name == "" {
+		return settings, "", nil
+	}
+
+	profileSettings, err := s.Configuration.ProfileSettings(name, program)
+	if err != nil {
+		return nil, "", err
+	}
+	merged, err := config.MergeSettings(*settings, *profileSettings)
+	if err != nil {
+		return nil, "", fmt.Errorf("error merging profile %s: %w", name, err)
+	}
+	return &merged, name, nil
 }
 
 func (s *DockerRunCommand) Execute() error {
-	docImage, command, settings, err := s.GetDockerMeta()
+	meta, err := s.GetDockerMeta()
+	if err != nil {
+		return err
+	}
+	ttyMode := config.TTYAuto
+	if meta.Program != nil && meta.Program.TTY != "" {
+		ttyMode = meta.Program.TTY
+	}
+	if s.Flags.Verbose {
+		printInvocation(meta)
+	}
+	if s.Flags.DryRun {
+		return s.dryRun(meta, ttyMode)
+	}
+	if meta.Program == nil {
+		return s.run(meta, ttyMode)
+	}
+
+	program := meta.Program
+	if err := runSteps("before", program.Before, []string{"CUBX_PROGRAM=" + program.Name}); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = s.run(meta, ttyMode)
+	exitCode := ExitCode(err)
+
+	if stepErr := runSteps("after", program.After, afterStepEnv(program.Name, exitCode, time.Since(start))); stepErr != nil {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cubx: %v\n", stepErr)
+			return err
+		}
+		return stepErr
+	}
+	return err
+}
+
+// run starts the container and returns an ExitError when the program fails.
+func (s *DockerRunCommand) run(meta *DockerMeta, ttyMode string) error {
+	exitCode, err := docker.RunImageAndCommand(meta.Image, meta.Args, ttyMode, s.Flags, meta.Settings)
 	if err != nil {
 		return err
 	}
-	return docker.RunImageAndCommand(docImage, command, s.Flags, settings)
+	if exitCode != 0 {
+		return &ExitError{Code: exitCode}
+	}
+	return nil
 }
 
 func handleProgram(tag string, _ string, args []string, programConfig config.Program) (string, string, []string, error) {
@@ -102,37 +208,41 @@ func handleProgram(tag string, _ string, args []string, programConfig config.Pro
 	return programConfig.Image, tag, arguments, nil
 }
 
-// resolveProgramSettings returns the program settings if they exist, otherwise returns the global settings
-func resolveProgramSettings(globalSettings, programSettings *config.Settings, hooks *[]config.Hook, additionalArgs []string) (*config.Settings, error) {
-	for _, hook := range *hooks {
-		escArgs, err := shlex.Split(strings.Join(additionalArgs, " "))
-		if err != nil {
-			return nil, fmt.Errorf("error parsing hook command: %w", err)
-		}
-
-		hookParts, err := shlex.Split(hook.Command)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing hook command: %w", err)
-		}
+// resolveProgramSettings returns the program settings, which hold the settings
+// of the matched hooks, if they exist, otherwise returns the global settings
+func resolveProgramSettings(globalSettings *config.Settings, program *config.Program, hooks []config.Hook) *config.Settings {
+	if len(hooks) > 0 || !program.Settings.IsEmpty() {
+		return &program.Settings
+	}
 
-		argsString := strings.Join(escArgs, " ")
-		hookString := strings.Join(hookParts, " ")
+	return globalSettings
+}
 
-		if strings.HasPrefix(argsString, hookString) {
-			return &hook.Settings, nil
-		}
+// withCaches adds the cache volumes of the program to the settings. Mounts of
+// the settings at the same target take precedence.
+func withCaches(settings *config.Settings, program *config.Program) (*config.Settings, error) {
+	caches, err := program.CacheMounts()
+	if err != nil {
+		return nil, fmt.Errorf("error resolving caches: %w", err)
 	}
-
-	if !programSettings.IsEmpty() {
-		return programSettings, nil
+	if len(caches) == 0 {
+		return settings, nil
 	}
 
-	return globalSettings, nil
+	merged, err := config.MergeSettings(config.Settings{Mounts: caches}, *settings)
+	if err != nil {
+		return nil, fmt.Errorf("error merging caches: %w", err)
+	}
+	return &merged, nil
 }
 
 func mergeFlagsWithSettings(programSettings *config.Settings, flags config.CLI) (*config.Settings, error) {
 	flagsSetting := config.Settings{
 		IgnorePaths: flags.FileIgnores,
+		Expose:      flags.Expose,
+		Env:         flags.Env,
+		EnvFile:     flags.EnvFiles,
+		Ports:       flags.Ports,
 	}
 
 	merged, err := config.MergeSettings(*programSettings, flagsSetting)
@@ -140,5 +250,31 @@ func mergeFlagsWithSettings(programSettings *config.Settings, flags config.CLI)
 		return nil, fmt.Errorf("error merging command config: %w", err)
 	}
 
+	// The flags may conflict with the config, e.g. --publish with net: host
+	if err := config.ValidateSettings(&merged); err != nil {
+		return nil, err
+	}
+
 	return &merged, nil
 }
+
+// printInvocation reports the matched program and hooks on stderr.
+func printInvocation(meta *DockerMeta) {
+	if meta.Program == nil {
+		fmt.Fprintf(os.Stderr, "cubx: no program configured, running in %s\n", meta.Image)
+		return
+	}
+	hooks := "none"
+	if len(meta.Hooks) > 0 {
+		var labels []string
+		for _, hook := range meta.Hooks {
+			labels = append(labels, hook.Label())
+		}
+		hooks = strings.Join(labels, ", ")
+	}
+	profile := meta.Profile
+	if profile == "" {
+		profile = "none"
+	}
+	fmt.Fprintf(os.Stderr, "cubx: program %s, hooks: %s, profile: %s, image %s\n", meta.Program.Name, hooks, profile, meta.Image)
+}
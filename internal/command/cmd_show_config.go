@@ -12,7 +12,16 @@ type ShowConfigCommand struct {
 }
 
 func (c *ShowConfigCommand) Execute() error {
-	for _, programConfig := range c.Configuration.Programs {
+	configuration := c.Configuration
+	if c.Flags.ShowRaw {
+		rawConfiguration, _, err := config.LoadRawConfig(true)
+		if err != nil {
+			return fmt.Errorf("error loading raw config: %w", err)
+		}
+		configuration = rawConfiguration
+	}
+
+	for _, programConfig := range configuration.Programs {
 		if c.Flags.ShowConfig == programConfig.Name {
 			tui.PrintColorizedYAML(programConfig)
 			return nil
@@ -11,12 +11,26 @@ type Command interface {
 	Execute() error
 }
 
+// IsConfigCommand reports whether the arguments invoke a `cubx config` subcommand.
+func IsConfigCommand(commandArgs []string) bool {
+	return len(commandArgs) > 0 && commandArgs[0] == "config"
+}
+
+// IsCacheCommand reports whether the arguments invoke a `cubx cache` subcommand.
+func IsCacheCommand(commandArgs []string) bool {
+	return len(commandArgs) > 0 && commandArgs[0] == "cache"
+}
+
 func Execute(commandArgs []string, flags config.CLI, configuration *config.ProgramConfig) error {
 	var command Command
 	if flags.ShowConfig != "" {
 		command = &ShowConfigCommand{Flags: flags, Configuration: configuration}
 	} else if flags.Session {
 		command = &SessionCommand{Flags: flags, Configuration: configuration}
+	} else if IsConfigCommand(commandArgs) {
+		command = &ConfigCommand{Flags: flags, Configuration: configuration, Args: commandArgs[1:]}
+	} else if IsCacheCommand(commandArgs) {
+		command = &CacheCommand{Flags: flags, Configuration: configuration, Args: commandArgs[1:]}
 	} else if len(commandArgs) > 0 {
 		command = &DockerRunCommand{Flags: flags, Configuration: configuration, CommandArgs: commandArgs}
 	}
@@ -0,0 +1,123 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/eddort/cubx/internal/docker"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dryRunReport describes everything an invocation resolves to.
+type dryRunReport struct {
+	Program    string        `yaml:"program,omitempty" json:"program,omitempty"`
+	Hooks      []string      `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+	Profile    string        `yaml:"profile,omitempty" json:"profile,omitempty"`
+	Image      string        `yaml:"image" json:"image"`
+	Platform   string        `yaml:"platform,omitempty" json:"platform,omitempty"`
+	Dockerfile string        `yaml:"dockerfile,omitempty" json:"dockerfile,omitempty"`
+	Args       []string      `yaml:"args" json:"args"`
+	TTY        bool          `yaml:"tty" json:"tty"`
+	WorkingDir string        `yaml:"working_dir" json:"working_dir"`
+	Network    string        `yaml:"network" json:"network"`
+	Init       bool          `yaml:"init,omitempty" json:"init,omitempty"`
+	Ports      []string      `yaml:"ports,omitempty" json:"ports,omitempty"`
+	Env        []string      `yaml:"env" json:"env"`
+	Mounts     []dryRunMount `yaml:"mounts" json:"mounts"`
+	Binds      []string      `yaml:"binds,omitempty" json:"binds,omitempty"`
+	Before     []string      `yaml:"before,omitempty" json:"before,omitempty"`
+	After      []string      `yaml:"after,omitempty" json:"after,omitempty"`
+	DockerRun  string        `yaml:"docker_run" json:"docker_run"`
+}
+
+type dryRunMount struct {
+	Type        string            `yaml:"type" json:"type"`
+	Source      string            `yaml:"source,omitempty" json:"source,omitempty"`
+	Target      string            `yaml:"target" json:"target"`
+	ReadOnly    bool              `yaml:"readonly,omitempty" json:"readonly,omitempty"`
+	Propagation string            `yaml:"propagation,omitempty" json:"propagation,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	// Size in bytes and octal Mode of a tmpfs mount
+	Size int64  `yaml:"size,omitempty" json:"size,omitempty"`
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+}
+
+// dryRun prints the container the invocation would run without pulling,
+// building or creating anything.
+func (s *DockerRunCommand) dryRun(meta *DockerMeta, ttyMode string) error {
+	spec, err := docker.PlanRun(meta.Image, meta.Args, ttyMode, meta.Settings)
+	if err != nil {
+		return err
+	}
+	// The output often ends up in issues and CI logs
+	if !s.Flags.ShowSecrets {
+		spec.MaskHostEnv()
+	}
+
+	report := dryRunReport{
+		Image:      meta.Image,
+		Profile:    meta.Profile,
+		Platform:   spec.Platform,
+		Args:       meta.Args,
+		TTY:        spec.Config.Tty,
+		WorkingDir: spec.Config.WorkingDir,
+		Network:    string(spec.HostConfig.NetworkMode),
+		Init:       spec.HostConfig.Init != nil && *spec.HostConfig.Init,
+		Ports:      meta.Settings.Ports,
+		Env:        spec.Config.Env,
+		Binds:      spec.HostConfig.Binds,
+		DockerRun:  strings.Join(spec.DockerRunArgs(), " "),
+	}
+	if meta.Program != nil {
+		report.Program = meta.Program.Name
+		report.Dockerfile = meta.Program.Dockerfile
+		for _, step := range meta.Program.Before {
+			report.Before = append(report.Before, step.String())
+		}
+		for _, step := range meta.Program.After {
+			report.After = append(report.After, step.String())
+		}
+	}
+	for _, hook := range meta.Hooks {
+		report.Hooks = append(report.Hooks, hook.Label())
+	}
+	for _, m := range spec.HostConfig.Mounts {
+		reported := dryRunMount{
+			Type:     string(m.Type),
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		}
+		if m.BindOptions != nil {
+			reported.Propagation = string(m.BindOptions.Propagation)
+		}
+		if m.VolumeOptions != nil {
+			reported.Labels = m.VolumeOptions.Labels
+		}
+		if m.TmpfsOptions != nil {
+			reported.Size = m.TmpfsOptions.SizeBytes
+			if m.TmpfsOptions.Mode != 0 {
+				reported.Mode = fmt.Sprintf("%o", m.TmpfsOptions.Mode)
+			}
+		}
+		report.Mounts = append(report.Mounts, reported)
+	}
+
+	var data []byte
+	switch s.Flags.Output {
+	case "", "yaml":
+		data, err = yaml.Marshal(report)
+	case "json":
+		data, err = json.MarshalIndent(report, "", "  ")
+		data = append(data, '\n')
+	default:
+		return fmt.Errorf("unknown output format: %s, expected yaml or json", s.Flags.Output)
+	}
+	if err != nil {
+		return fmt.Errorf("error encoding the dry run: %w", err)
+	}
+	fmt.Print(string(data))
+	return nil
+}
@@ -0,0 +1,36 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ExitCodeFailure is the exit code used when cubx itself or the Docker daemon
+// fails. It follows the `docker run` convention so that it does not collide
+// with the usual exit codes of the containerized program.
+const ExitCodeFailure = 125
+
+// ExitError is returned when the containerized program ran but finished with
+// a non-zero status. Code holds the container's exit status, which Docker
+// reports as 128+signal for processes killed by a signal.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("program exited with status %d", e.Code)
+}
+
+// ExitCode maps an error returned by Execute to the exit code of the cubx
+// process: the program's own status for an ExitError, ExitCodeFailure for
+// any other error and 0 for nil.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitCodeFailure
+}
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/eddort/cubx/internal/config"
+	"github.com/eddort/cubx/internal/tui"
+
+	"github.com/google/shlex"
+)
+
+// stepDepthEnv counts nested cubx invocations started by steps, so that a
+// program whose steps invoke itself fails instead of recursing forever.
+const (
+	stepDepthEnv = "CUBX_STEP_DEPTH"
+	maxStepDepth = 5
+)
+
+// runSteps runs the before or after steps of a program in order and stops at
+// the first failing one. env is added to the environment of every step.
+func runSteps(stage string, steps []config.Step, env []string) error {
+	for _, step := range steps {
+		fmt.Fprintf(os.Stderr, "%scubx: %s: %s%s\n", tui.ColorBlue, stage, step, tui.ColorReset)
+		if err := runStep(step, env); err != nil {
+			return fmt.Errorf("%s step %q failed: %w", stage, step.String(), err)
+		}
+	}
+	return nil
+}
+
+// runStep runs a host command in the shell or a cubx program with the
+// standard streams of cubx.
+func runStep(step config.Step, env []string) error {
+	var cmd *exec.Cmd
+	if step.Cubx != "" {
+		depth, _ := strconv.Atoi(os.Getenv(stepDepthEnv))
+		if depth >= maxStepDepth {
+			return fmt.Errorf("too many nested cubx steps")
+		}
+		args, err := shlex.Split(step.Cubx)
+		if err != nil {
+			return fmt.Errorf("error parsing step: %w", err)
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("error locating the cubx executable: %w", err)
+		}
+		cmd = exec.Command(executable, args...)
+		env = append(env, fmt.Sprintf("%s=%d", stepDepthEnv, depth+1))
+	} else {
+		cmd = exec.Command("sh", "-c", step.Run)
+	}
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Run()
+}
+
+// afterStepEnv describes the finished run to the after steps.
+func afterStepEnv(program string, exitCode int, duration time.Duration) []string {
+	return []string{
+		"CUBX_PROGRAM=" + program,
+		fmt.Sprintf("CUBX_EXIT_CODE=%d", exitCode),
+		fmt.Sprintf("CUBX_DURATION=%.3f", duration.Seconds()),
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+)
+
+// Scopes of the cache volumes of a program. Project caches are kept per
+// project root, global caches are shared by all projects.
+const (
+	CacheScopeProject = "project"
+	CacheScopeGlobal  = "global"
+)
+
+// Labels of the volumes that back program caches.
+const (
+	CacheProgramLabel = "cubx.cache.program"
+	CachePathLabel    = "cubx.cache.path"
+	CacheScopeLabel   = "cubx.cache.scope"
+	CacheProjectLabel = "cubx.cache.project"
+)
+
+var volumeNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// Scope returns the cache scope of the program, project when it is not set.
+func (p *Program) Scope() string {
+	if p.CacheScope == "" {
+		return CacheScopeProject
+	}
+	return p.CacheScope
+}
+
+// CacheMounts returns the named volumes that back the cache directories of
+// the program. The volume names depend on the program, the directory and, for
+// project caches, the project root, so that each cache is reused by later runs.
+func (p *Program) CacheMounts() ([]Mount, error) {
+	if len(p.Cache) == 0 {
+		return nil, nil
+	}
+
+	project := ""
+	if p.Scope() == CacheScopeProject {
+		root, err := ProjectRoot()
+		if err != nil {
+			return nil, err
+		}
+		project = root
+	}
+
+	var mounts []Mount
+	for _, path := range p.Cache {
+		labels := map[string]string{
+			CacheProgramLabel: p.Name,
+			CachePathLabel:    path,
+			CacheScopeLabel:   p.Scope(),
+		}
+		if project != "" {
+			labels[CacheProjectLabel] = project
+		}
+		mounts = append(mounts, Mount{
+			Type:   MountTypeVolume,
+			Source: cacheVolumeName(p.Name, path, project),
+			Target: path,
+			Labels: labels,
+		})
+	}
+	return mounts, nil
+}
+
+// cacheVolumeName names the volume of a cache directory, project is empty for
+// global caches.
+func cacheVolumeName(program, path, project string) string {
+	sum := sha256.Sum256([]byte(project + "\x00" + path))
+	return "cubx-cache-" + volumeNameInvalidChars.ReplaceAllString(program, "-") + "-" + hex.EncodeToString(sum[:6])
+}
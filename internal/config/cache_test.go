@@ -0,0 +1,80 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProgramCacheMounts(t *testing.T) {
+	projectRoot, err := ProjectRoot()
+	if err != nil {
+		t.Fatalf("Failed to resolve the project root: %v", err)
+	}
+
+	project := &Program{Name: "npm", Cache: []string{"/root/.npm"}}
+	mounts, err := project.CacheMounts()
+	if err != nil {
+		t.Fatalf("CacheMounts failed: %v", err)
+	}
+	if len(mounts) != 1 {
+		t.Fatalf("Expected one cache mount, got %v", mounts)
+	}
+	m := mounts[0]
+	if m.Type != MountTypeVolume || m.Target != "/root/.npm" || !strings.HasPrefix(m.Source, "cubx-cache-npm-") {
+		t.Errorf("Unexpected cache mount %+v", m)
+	}
+	if m.Labels[CacheScopeLabel] != CacheScopeProject || m.Labels[CacheProjectLabel] != projectRoot || m.Labels[CachePathLabel] != "/root/.npm" {
+		t.Errorf("Unexpected cache labels %v", m.Labels)
+	}
+
+	again, err := project.CacheMounts()
+	if err != nil {
+		t.Fatalf("CacheMounts failed: %v", err)
+	}
+	if again[0].Source != m.Source {
+		t.Errorf("Expected a stable volume name, got %s and %s", m.Source, again[0].Source)
+	}
+
+	global := &Program{Name: "npm", Cache: []string{"/root/.npm"}, CacheScope: CacheScopeGlobal}
+	globalMounts, err := global.CacheMounts()
+	if err != nil {
+		t.Fatalf("CacheMounts failed: %v", err)
+	}
+	if globalMounts[0].Source == m.Source {
+		t.Errorf("Expected project and global caches to use different volumes")
+	}
+	if _, ok := globalMounts[0].Labels[CacheProjectLabel]; ok {
+		t.Errorf("Expected no project label on a global cache")
+	}
+
+	if name := cacheVolumeName("my tool/v2", "/cache", ""); !strings.HasPrefix(name, "cubx-cache-my-tool-v2-") {
+		t.Errorf("Unexpected volume name %s", name)
+	}
+}
+
+func TestLoadConfigFileInvalidCache(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		err     string
+	}{
+		"relative": {err: "'.npm' must start with /", content: `
+programs:
+  - name: npm
+    image: node
+    cache: [.npm]
+`},
+		"scope": {err: "'user' is not a valid value, expected one of: project, global", content: `
+programs:
+  - name: npm
+    image: node
+    cache: [/root/.npm]
+    cache_scope: user
+`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assertLoadError(t, test.content, test.err)
+		})
+	}
+}
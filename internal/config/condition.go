@@ -0,0 +1,191 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/moby/term"
+)
+
+// Condition restricts a program, hook or settings block to the hosts it
+// matches. Every criterion that is set must match.
+type Condition struct {
+	OS   string `yaml:"os,omitempty" validate:"oneof='' linux darwin"`
+	Arch string `yaml:"arch,omitempty" validate:"oneof='' amd64 arm64 386 arm"`
+	// Env entries are either NAME, which must be set, or NAME=value
+	Env []string `yaml:"env,omitempty" validate:"dive,env"`
+	// Exists lists files that must exist, relative to the project root
+	Exists []string `yaml:"exists,omitempty"`
+	// TTY requires cubx to run in a terminal, or not to
+	TTY *bool      `yaml:"tty,omitempty"`
+	Not *Condition `yaml:"not,omitempty"`
+}
+
+// SkippedBlock is a config block dropped because its condition does not match.
+type SkippedBlock struct {
+	Path   string
+	Reason string
+}
+
+// Matches reports whether the condition matches the host. When it does not,
+// the reason names the first criterion that failed.
+func (c *Condition) Matches() (bool, string, error) {
+	if c == nil {
+		return true, "", nil
+	}
+
+	if c.OS != "" && c.OS != runtime.GOOS {
+		return false, fmt.Sprintf("os is %s, expected %s", runtime.GOOS, c.OS), nil
+	}
+	if c.Arch != "" && c.Arch != runtime.GOARCH {
+		return false, fmt.Sprintf("arch is %s, expected %s", runtime.GOARCH, c.Arch), nil
+	}
+
+	for _, env := range c.Env {
+		name, expected, hasValue := strings.Cut(env, "=")
+		value, exists := os.LookupEnv(name)
+		if !exists {
+			return false, fmt.Sprintf("%s is not set", name), nil
+		}
+		if hasValue && value != expected {
+			return false, fmt.Sprintf("%s is %q, expected %q", name, value, expected), nil
+		}
+	}
+
+	if len(c.Exists) > 0 {
+		projectRoot, err := ProjectRoot()
+		if err != nil {
+			return false, "", err
+		}
+		for _, file := range c.Exists {
+			path := file
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(projectRoot, path)
+			}
+			if _, err := os.Stat(path); err != nil {
+				return false, fmt.Sprintf("%s does not exist", file), nil
+			}
+		}
+	}
+
+	if c.TTY != nil {
+		_, stdinTerminal := term.GetFdInfo(os.Stdin)
+		_, stdoutTerminal := term.GetFdInfo(os.Stdout)
+		if tty := stdinTerminal && stdoutTerminal; tty != *c.TTY {
+			if tty {
+				return false, "running in a terminal", nil
+			}
+			return false, "not running in a terminal", nil
+		}
+	}
+
+	if c.Not != nil {
+		matched, _, err := c.Not.Matches()
+		if err != nil {
+			return false, "", err
+		}
+		if matched {
+			return false, "the not condition matches", nil
+		}
+	}
+
+	return true, "", nil
+}
+
+// applyConditions drops the programs, hooks and settings blocks of a config
+// layer whose condition does not match the host and clears the conditions of
+// the remaining ones. The dropped blocks are returned.
+func applyConditions(config *ProgramConfig) ([]SkippedBlock, error) {
+	var skipped []SkippedBlock
+	check := func(condition *Condition, path string) (bool, error) {
+		matched, reason, err := condition.Matches()
+		if err != nil {
+			return false, fmt.Errorf("error evaluating the condition of %s: %w", path, err)
+		}
+		if !matched {
+			skipped = append(skipped, SkippedBlock{Path: path, Reason: reason})
+		}
+		return matched, nil
+	}
+	settings := func(settings *Settings, path string) error {
+		matched, err := check(settings.When, path)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			*settings = Settings{}
+		}
+		settings.When = nil
+		return nil
+	}
+
+	if err := settings(&config.Settings, "settings"); err != nil {
+		return nil, err
+	}
+
+	for i := range config.Rules {
+		if err := settings(&config.Rules[i].Settings, fmt.Sprintf("rules[%d].settings", i)); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, profile := range config.Profiles {
+		path := "profiles." + name
+		if err := settings(&profile.Settings, path+".settings"); err != nil {
+			return nil, err
+		}
+		for program, programSettings := range profile.Programs {
+			if err := settings(&programSettings, path+".programs."+program); err != nil {
+				return nil, err
+			}
+			profile.Programs[program] = programSettings
+		}
+		config.Profiles[name] = profile
+	}
+
+	var programs []Program
+	for _, program := range config.Programs {
+		path := fmt.Sprintf("programs[%s]", program.Name)
+		matched, err := check(program.When, path)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		program.When = nil
+
+		if err := settings(&program.Settings, path+".settings"); err != nil {
+			return nil, err
+		}
+
+		var hooks []Hook
+		for _, hook := range program.Hooks {
+			hookPath := fmt.Sprintf("%s.hooks[%s]", path, hook.Label())
+			matched, err := check(hook.When, hookPath)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+			hook.When = nil
+			if err := settings(&hook.Settings, hookPath+".settings"); err != nil {
+				return nil, err
+			}
+			hooks = append(hooks, hook)
+		}
+		if program.Hooks != nil {
+			program.Hooks = hooks
+		}
+		programs = append(programs, program)
+	}
+	if config.Programs != nil {
+		config.Programs = programs
+	}
+
+	return skipped, nil
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConditionMatches(t *testing.T) {
+	t.Setenv("CUBX_TEST_MODE", "dev")
+	t.Setenv("CUBX_TEST_UNSET", "")
+	os.Unsetenv("CUBX_TEST_UNSET")
+
+	otherOS := "darwin"
+	if runtime.GOOS == "darwin" {
+		otherOS = "linux"
+	}
+	projectRoot, err := ProjectRoot()
+	if err != nil {
+		t.Fatalf("Failed to find project root: %v", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+	relFile, err := filepath.Rel(projectRoot, filepath.Join(wd, "condition_test.go"))
+	if err != nil {
+		t.Fatalf("Failed to make path relative: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		condition *Condition
+		expected  bool
+		reason    string
+	}{
+		{name: "nil", condition: nil, expected: true},
+		{name: "host os", condition: &Condition{OS: runtime.GOOS, Arch: runtime.GOARCH}, expected: true},
+		{name: "other os", condition: &Condition{OS: otherOS}, reason: fmt.Sprintf("os is %s, expected %s", runtime.GOOS, otherOS)},
+		{name: "env present", condition: &Condition{Env: []string{"CUBX_TEST_MODE"}}, expected: true},
+		{name: "env equal", condition: &Condition{Env: []string{"CUBX_TEST_MODE=dev"}}, expected: true},
+		{name: "env different", condition: &Condition{Env: []string{"CUBX_TEST_MODE=ci"}}, reason: `CUBX_TEST_MODE is "dev", expected "ci"`},
+		{name: "env unset", condition: &Condition{Env: []string{"CUBX_TEST_UNSET"}}, reason: "CUBX_TEST_UNSET is not set"},
+		{name: "not env", condition: &Condition{Not: &Condition{Env: []string{"CUBX_TEST_UNSET"}}}, expected: true},
+		{name: "file exists", condition: &Condition{Exists: []string{relFile}}, expected: true},
+		{name: "file missing", condition: &Condition{Exists: []string{"missing.txt"}}, reason: "missing.txt does not exist"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matched, reason, err := test.condition.Matches()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if matched != test.expected || reason != test.reason {
+				t.Errorf("Matches() = %v, %q, expected %v, %q", matched, reason, test.expected, test.reason)
+			}
+		})
+	}
+}
+
+func TestApplyConditions(t *testing.T) {
+	t.Setenv("CUBX_TEST_CI", "")
+	os.Unsetenv("CUBX_TEST_CI")
+	onCI := &Condition{Env: []string{"CUBX_TEST_CI"}}
+
+	config := &ProgramConfig{
+		Settings: Settings{When: onCI, Net: "none"},
+		Programs: []Program{
+			{Name: "forge", Image: "foundry", Settings: Settings{When: &Condition{Not: onCI}, Net: "host"}},
+			{Name: "forge", When: onCI, Settings: Settings{Platform: "linux/amd64"}},
+			{
+				Name:  "npm",
+				Image: "node",
+				Hooks: []Hook{
+					{Command: "publish", When: onCI},
+					{Command: "install", Settings: Settings{When: onCI, Net: "bridge"}},
+				},
+			},
+		},
+	}
+
+	skipped, err := applyConditions(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedSkipped := []SkippedBlock{
+		{Path: "settings", Reason: "CUBX_TEST_CI is not set"},
+		{Path: "programs[forge]", Reason: "CUBX_TEST_CI is not set"},
+		{Path: "programs[npm].hooks[publish]", Reason: "CUBX_TEST_CI is not set"},
+		{Path: "programs[npm].hooks[install].settings", Reason: "CUBX_TEST_CI is not set"},
+	}
+	if diff := cmp.Diff(expectedSkipped, skipped); diff != "" {
+		t.Errorf("Unexpected skipped blocks (-want +got):\n%s", diff)
+	}
+
+	expected := &ProgramConfig{
+		Programs: []Program{
+			{Name: "forge", Image: "foundry", Settings: Settings{Net: "host"}},
+			{Name: "npm", Image: "node", Hooks: []Hook{{Command: "install"}}},
+		},
+	}
+	if diff := cmp.Diff(expected, config); diff != "" {
+		t.Errorf("Unexpected config (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadConfigFileSkippedBlocks(t *testing.T) {
+	t.Setenv("CUBX_TEST_CI", "")
+	os.Unsetenv("CUBX_TEST_CI")
+	t.Setenv("CUBX_TEST_CI_TOKEN", "")
+	os.Unsetenv("CUBX_TEST_CI_TOKEN")
+
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	writeConfigFiles(t, map[string]string{
+		configPath: `
+settings:
+  when: {env: [CUBX_TEST_CI]}
+  env: ["T=${env:CUBX_TEST_CI_TOKEN}"]
+programs:
+  - name: deploy
+    image: alpine
+    when: {env: [CUBX_TEST_CI]}
+    settings:
+      ports: ["${env:CUBX_TEST_CI_TOKEN}"]
+  - name: forge
+    image: ghcr.io/foundry-rs/foundry
+    hooks:
+      - command: script
+        when: {env: [CUBX_TEST_CI]}
+        tag: ${env:CUBX_TEST_CI_TOKEN}
+`,
+	})
+
+	config, err := loadConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("Expected skipped blocks not to be expanded or validated, got %v", err)
+	}
+	if _, err := applyConditions(config); err != nil {
+		t.Fatalf("applyConditions failed: %v", err)
+	}
+	if len(config.Settings.Env) != 0 || len(config.Programs) != 1 || len(config.Programs[0].Hooks) != 0 {
+		t.Errorf("Expected the skipped blocks to be dropped, got %+v", config)
+	}
+
+	// The same blocks are expanded when their condition matches
+	t.Setenv("CUBX_TEST_CI", "1")
+	if _, err := loadConfigFile(configPath); err == nil {
+		t.Errorf("Expected an undefined variable error when the condition matches")
+	}
+}
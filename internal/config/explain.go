@@ -0,0 +1,482 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// explainSkipFields are the fields that only steer merging and are not
+// part of the effective program.
+var explainSkipFields = map[string]bool{
+	"Name":     true,
+	"Replace":  true,
+	"Disabled": true,
+}
+
+// Origin is the place a config value was declared at.
+type Origin struct {
+	// Layer is one of defaults, user or project
+	Layer  string
+	File   string
+	Line   int
+	Column int
+	// Scope tells how the value reached the program when it was not declared
+	// by the program itself, e.g. global settings or extends npm
+	Scope string
+}
+
+func (o Origin) String() string {
+	location := o.Layer
+	if o.File != "" {
+		location = fmt.Sprintf("%s %s", location, o.File)
+		if o.Line > 0 {
+			location = fmt.Sprintf("%s:%d:%d", location, o.Line, o.Column)
+		}
+	}
+	if o.Scope != "" {
+		location = fmt.Sprintf("%s (%s)", location, o.Scope)
+	}
+	return location
+}
+
+// OverriddenValue is a value of a lower layer that lost against the effective one.
+type OverriddenValue struct {
+	Value  string
+	Origin Origin
+}
+
+// ExplainedValue is an effective value of a program together with its origin.
+// Origin is nil for values cubx computed rather than read from a config.
+// List items are addressed by their value, or by the variable name for env.
+type ExplainedValue struct {
+	Path       string
+	Value      string
+	Origin     *Origin
+	Overridden []OverriddenValue
+}
+
+// SkippedOrigin is a block that was dropped because its when condition does
+// not match the host.
+type SkippedOrigin struct {
+	SkippedBlock
+	Origin Origin
+}
+
+// Explanation lists the effective values of a program and the blocks affecting
+// the program that were skipped.
+type Explanation struct {
+	Values  []ExplainedValue
+	Skipped []SkippedOrigin
+}
+
+// configLeaf is a scalar value of a config and the node it was read from.
+type configLeaf struct {
+	path  string
+	value string
+	node  *yaml.Node
+}
+
+// declaration is a value declared by one of the config layers.
+// Declarations sort by source, then rank, then order within the source.
+type declaration struct {
+	configLeaf
+	origin Origin
+	source int
+	rank   int
+	order  int
+}
+
+// Declaration ranks within a source: rules win over global settings, program
+// values over rules and hook values over program values.
+const (
+	rankGlobal = iota
+	rankRule
+	rankAncestor
+	rankProgram
+	rankAncestorHook
+	rankProgramHook
+)
+
+// Explain loads the configuration and reports every effective value of the
+// program with the layer and file position it came from, including the items
+// union-merged from the global settings into program and hook settings, and
+// lists the blocks affecting the program that were skipped by their condition.
+func Explain(name string) (*Explanation, error) {
+	loader, finalConfig, err := loadLayers(true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var program *Program
+	for i := range finalConfig.Programs {
+		if finalConfig.Programs[i].Name == name {
+			program = &finalConfig.Programs[i]
+		}
+	}
+	if program == nil {
+		return nil, fmt.Errorf("not found command: %s", name)
+	}
+
+	var effective []configLeaf
+	flattenValue(reflect.ValueOf(*program), nil, "", &effective)
+
+	var hooks []string
+	for i, hook := range program.Hooks {
+		hooks = append(hooks, fmt.Sprintf("hooks[%s]", flattenLabel("hooks", reflect.ValueOf(hook), i)))
+	}
+	collector := &declarationCollector{
+		program:   name,
+		effective: program,
+		ancestors: extendsChain(finalConfig, program),
+		hooks:     hooks,
+	}
+
+	if err := collector.collect(getProgramConfig(), nil, Origin{Layer: layerDefaults}, 0); err != nil {
+		return nil, err
+	}
+	for i, file := range loader.files {
+		root, err := parseConfigNode(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := collector.collect(loader.configs[i], root, Origin{Layer: loader.layers[i], File: displayPath(file)}, i+1); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(collector.declarations, func(i, j int) bool {
+		a, b := collector.declarations[i], collector.declarations[j]
+		if a.source != b.source {
+			return a.source < b.source
+		}
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		return a.order < b.order
+	})
+
+	explanation := &Explanation{Skipped: collector.skipped}
+	for _, leaf := range effective {
+		explanation.Values = append(explanation.Values, explainLeaf(leaf, collector.declarations))
+	}
+	return explanation, nil
+}
+
+// explainLeaf attributes an effective value to the last declaration of the
+// same value and lists the different values declared before it.
+func explainLeaf(leaf configLeaf, declarations []declaration) ExplainedValue {
+	result := ExplainedValue{Path: leaf.path, Value: leaf.value}
+
+	winner := -1
+	for i, decl := range declarations {
+		if decl.path == leaf.path && decl.value == leaf.value {
+			winner = i
+		}
+	}
+	if winner == -1 {
+		return result
+	}
+	origin := declarations[winner].origin
+	result.Origin = &origin
+
+	for _, decl := range declarations[:winner] {
+		if decl.path == leaf.path && decl.value != leaf.value {
+			result.Overridden = append(result.Overridden, OverriddenValue{Value: decl.value, Origin: decl.origin})
+		}
+	}
+	return result
+}
+
+// declarationCollector gathers the values a config layer declares for the
+// explained program, mapped to the paths of the effective program.
+type declarationCollector struct {
+	program      string
+	effective    *Program
+	ancestors    []string
+	hooks        []string
+	declarations []declaration
+	skipped      []SkippedOrigin
+}
+
+// matches evaluates the condition of a block and records the block as skipped
+// when it does not match.
+func (c *declarationCollector) matches(condition *Condition, path string, node *yaml.Node, origin Origin) (bool, error) {
+	matched, reason, err := condition.Matches()
+	if err != nil || matched {
+		return matched, err
+	}
+	if node != nil {
+		origin.Line, origin.Column = node.Line, node.Column
+	}
+	c.skipped = append(c.skipped, SkippedOrigin{SkippedBlock: SkippedBlock{Path: path, Reason: reason}, Origin: origin})
+	return false, nil
+}
+
+func (c *declarationCollector) collect(config *ProgramConfig, root *yaml.Node, origin Origin, source int) error {
+	root = contentNode(root)
+
+	globalOrigin := origin
+	globalOrigin.Scope = "global settings"
+	settingsNode := mappingValue(root, "settings")
+	matched, err := c.matches(config.Settings.When, "settings", settingsNode, globalOrigin)
+	if err != nil {
+		return err
+	}
+	if matched {
+		var global []configLeaf
+		flattenValue(reflect.ValueOf(config.Settings), settingsNode, "settings", &global)
+		for _, leaf := range global {
+			c.add(leaf, leaf.path, globalOrigin, source, rankGlobal)
+			for _, hook := range c.hooks {
+				c.add(leaf, joinPath(hook, leaf.path), globalOrigin, source, rankGlobal)
+			}
+		}
+	}
+
+	rulesNode := mappingValue(root, "rules")
+	for i, rule := range config.Rules {
+		if !rule.Matches(c.effective) {
+			continue
+		}
+		var node *yaml.Node
+		if rulesNode != nil && rulesNode.Kind == yaml.SequenceNode && i < len(rulesNode.Content) {
+			node = mappingValue(rulesNode.Content[i], "settings")
+		}
+		ruleOrigin := origin
+		ruleOrigin.Scope = fmt.Sprintf("rules[%d]", i)
+		matched, err := c.matches(rule.Settings.When, ruleOrigin.Scope+".settings", node, ruleOrigin)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+
+		var leaves []configLeaf
+		flattenValue(reflect.ValueOf(rule.Settings), node, "settings", &leaves)
+		for _, leaf := range leaves {
+			c.add(leaf, leaf.path, ruleOrigin, source, rankRule)
+			for _, hook := range c.hooks {
+				c.add(leaf, joinPath(hook, leaf.path), ruleOrigin, source, rankRule)
+			}
+		}
+	}
+
+	programsNode := mappingValue(root, "programs")
+	for i, program := range config.Programs {
+		rank, hookRank := rankProgram, rankProgramHook
+		programOrigin := origin
+		switch {
+		case program.Name == c.program:
+		case contains(c.ancestors, program.Name):
+			rank, hookRank = rankAncestor, rankAncestorHook
+			programOrigin.Scope = "extends " + program.Name
+		default:
+			continue
+		}
+
+		var node *yaml.Node
+		if programsNode != nil && programsNode.Kind == yaml.SequenceNode && i < len(programsNode.Content) {
+			node = programsNode.Content[i]
+		}
+
+		path := fmt.Sprintf("programs[%s]", program.Name)
+		matched, err := c.matches(program.When, path, node, programOrigin)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+
+		// Prefixes of the leaves of the skipped settings and hooks
+		var skippedPrefixes []string
+		settingsNode := mappingValue(node, "settings")
+		matched, err = c.matches(program.Settings.When, path+".settings", settingsNode, programOrigin)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			skippedPrefixes = append(skippedPrefixes, "settings.")
+		}
+		hooksNode := mappingValue(node, "hooks")
+		for j, hook := range program.Hooks {
+			var hookNode *yaml.Node
+			if hooksNode != nil && hooksNode.Kind == yaml.SequenceNode && j < len(hooksNode.Content) {
+				hookNode = hooksNode.Content[j]
+			}
+			hookPath := fmt.Sprintf("hooks[%s]", flattenLabel("hooks", reflect.ValueOf(hook), j))
+			matched, err := c.matches(hook.When, path+"."+hookPath, hookNode, programOrigin)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				skippedPrefixes = append(skippedPrefixes, hookPath+".")
+				continue
+			}
+			matched, err = c.matches(hook.Settings.When, path+"."+hookPath+".settings", mappingValue(hookNode, "settings"), programOrigin)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				skippedPrefixes = append(skippedPrefixes, hookPath+".settings.")
+			}
+		}
+
+		var leaves []configLeaf
+		flattenValue(reflect.ValueOf(program), node, "", &leaves)
+		for _, leaf := range leaves {
+			if hasAnyPrefix(leaf.path, skippedPrefixes) {
+				continue
+			}
+			if strings.HasPrefix(leaf.path, "hooks[") {
+				c.add(leaf, leaf.path, programOrigin, source, hookRank)
+				continue
+			}
+			c.add(leaf, leaf.path, programOrigin, source, rank)
+			if strings.HasPrefix(leaf.path, "settings.") {
+				for _, hook := range c.hooks {
+					c.add(leaf, joinPath(hook, leaf.path), programOrigin, source, rank)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func hasAnyPrefix(value string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *declarationCollector) add(leaf configLeaf, path string, origin Origin, source, rank int) {
+	if leaf.node != nil {
+		origin.Line, origin.Column = leaf.node.Line, leaf.node.Column
+	}
+	leaf.path = path
+	c.declarations = append(c.declarations, declaration{
+		configLeaf: leaf,
+		origin:     origin,
+		source:     source,
+		rank:       rank,
+		order:      len(c.declarations),
+	})
+}
+
+// extendsChain lists the programs the program inherits from, closest first.
+func extendsChain(config *ProgramConfig, program *Program) []string {
+	var chain []string
+	for parent := program.Extends; parent != "" && parent != program.Name && !contains(chain, parent); {
+		chain = append(chain, parent)
+		next := ""
+		for _, candidate := range config.Programs {
+			if candidate.Name == parent {
+				next = candidate.Extends
+			}
+		}
+		parent = next
+	}
+	return chain
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseConfigNode reads the YAML tree of a config file for positions.
+func parseConfigNode(filePath string) (*yaml.Node, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file %s: %w", filePath, err)
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", filePath, err)
+	}
+	return &root, nil
+}
+
+// flattenValue lists the non-empty scalar values of v with their config path
+// and, when node is the YAML tree v was decoded from, the node of each value.
+func flattenValue(v reflect.Value, node *yaml.Node, path string, leaves *[]configLeaf) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			flattenValue(v.Elem(), node, path, leaves)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() || explainSkipFields[field.Name] {
+				continue
+			}
+			key := yamlKey(field)
+			fieldNode := node
+			// A struct written in its string form, such as a mount or a
+			// step, has no keys: every leaf points to the string
+			if node == nil || node.Kind != yaml.ScalarNode {
+				fieldNode = mappingValue(node, key)
+			}
+			flattenValue(v.Field(i), fieldNode, joinPath(path, key), leaves)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			var itemNode *yaml.Node
+			if node != nil && node.Kind == yaml.SequenceNode && i < len(node.Content) {
+				itemNode = node.Content[i]
+			}
+			item := reflect.Indirect(v.Index(i))
+			flattenValue(item, itemNode, fmt.Sprintf("%s[%s]", path, flattenLabel(path, item, i)), leaves)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			name := fmt.Sprint(key.Interface())
+			flattenValue(v.MapIndex(key), mappingValue(node, name), joinPath(path, name), leaves)
+		}
+	default:
+		if v.IsZero() {
+			return
+		}
+		*leaves = append(*leaves, configLeaf{path: path, value: fmt.Sprint(v.Interface()), node: node})
+	}
+}
+
+// flattenLabel identifies a list item independently of its position, so that
+// union-merged lists can be matched across layers.
+func flattenLabel(path string, item reflect.Value, index int) string {
+	switch item.Kind() {
+	case reflect.String:
+		if path == "env" || strings.HasSuffix(path, ".env") {
+			key, _, _ := strings.Cut(item.String(), "=")
+			return key
+		}
+		return item.String()
+	case reflect.Struct:
+		for _, name := range []string{"Name", "Command", "Target"} {
+			if field := item.FieldByName(name); field.IsValid() && field.Kind() == reflect.String && field.String() != "" {
+				return field.String()
+			}
+		}
+	}
+	return strconv.Itoa(index)
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExplain(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	homeDir := filepath.Join(tempDir, "home")
+	projectDir := filepath.Join(tempDir, "project")
+	if err := os.MkdirAll(filepath.Join(projectDir, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	userConfigPath := filepath.Join(homeDir, ".cubx", "config.yaml")
+	projectConfigPath := filepath.Join(projectDir, ".cubx", "config.yaml")
+	writeConfigFiles(t, map[string]string{
+		userConfigPath: `
+settings:
+  net: none
+  ignore_paths: [.env]
+programs:
+  - name: node
+    tag: "18"
+    settings:
+      mounts: ["/etc/ssl/certs:/etc/ssl/certs:ro"]
+    before: ["echo start"]
+`,
+		projectConfigPath: `
+programs:
+  - name: node
+    tag: "20"
+    hooks:
+      - command: test
+        settings:
+          net: bridge
+  - name: node
+    when:
+      env: [CUBX_TEST_UNSET]
+    tag: "21"
+`,
+	})
+
+	t.Setenv("CUBX_TEST_UNSET", "")
+	os.Unsetenv("CUBX_TEST_UNSET")
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", homeDir)
+	defer os.Setenv("HOME", oldHome)
+
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	explanation, err := Explain("node")
+	if err != nil {
+		t.Fatalf("Failed to explain program: %v", err)
+	}
+	explained := make(map[string]ExplainedValue)
+	for _, value := range explanation.Values {
+		explained[value.Path] = value
+	}
+
+	userFile := displayPath(userConfigPath)
+	projectFile := filepath.Join(".cubx", "config.yaml")
+	expected := map[string]ExplainedValue{
+		"image": {Path: "image", Value: "node", Origin: &Origin{Layer: layerDefaults}},
+		"tag": {
+			Path:       "tag",
+			Value:      "20",
+			Origin:     &Origin{Layer: layerProject, File: projectFile, Line: 4, Column: 10},
+			Overridden: []OverriddenValue{{Value: "18", Origin: Origin{Layer: layerUser, File: userFile, Line: 7, Column: 10}}},
+		},
+		"settings.ignore_paths[.env]": {
+			Path:   "settings.ignore_paths[.env]",
+			Value:  ".env",
+			Origin: &Origin{Layer: layerUser, File: userFile, Line: 4, Column: 18, Scope: "global settings"},
+		},
+		"hooks[test].settings.ignore_paths[.env]": {
+			Path:   "hooks[test].settings.ignore_paths[.env]",
+			Value:  ".env",
+			Origin: &Origin{Layer: layerUser, File: userFile, Line: 4, Column: 18, Scope: "global settings"},
+		},
+		"settings.mounts[/etc/ssl/certs].target": {
+			Path:   "settings.mounts[/etc/ssl/certs].target",
+			Value:  "/etc/ssl/certs",
+			Origin: &Origin{Layer: layerUser, File: userFile, Line: 9, Column: 16},
+		},
+		"settings.mounts[/etc/ssl/certs].readonly": {
+			Path:   "settings.mounts[/etc/ssl/certs].readonly",
+			Value:  "true",
+			Origin: &Origin{Layer: layerUser, File: userFile, Line: 9, Column: 16},
+		},
+		"before[0].run": {
+			Path:   "before[0].run",
+			Value:  "echo start",
+			Origin: &Origin{Layer: layerUser, File: userFile, Line: 10, Column: 14},
+		},
+		"hooks[test].settings.net": {
+			Path:       "hooks[test].settings.net",
+			Value:      "bridge",
+			Origin:     &Origin{Layer: layerProject, File: projectFile, Line: 8, Column: 16},
+			Overridden: []OverriddenValue{{Value: "none", Origin: Origin{Layer: layerUser, File: userFile, Line: 3, Column: 8, Scope: "global settings"}}},
+		},
+	}
+	for path, want := range expected {
+		if diff := cmp.Diff(want, explained[path]); diff != "" {
+			t.Errorf("Unexpected explanation of %s (-want +got):\n%s", path, diff)
+		}
+	}
+
+	expectedSkipped := []SkippedOrigin{{
+		SkippedBlock: SkippedBlock{Path: "programs[node]", Reason: "CUBX_TEST_UNSET is not set"},
+		Origin:       Origin{Layer: layerProject, File: projectFile, Line: 9, Column: 5},
+	}}
+	if diff := cmp.Diff(expectedSkipped, explanation.Skipped); diff != "" {
+		t.Errorf("Unexpected skipped blocks (-want +got):\n%s", diff)
+	}
+
+	if _, err := Explain("missing"); err == nil {
+		t.Errorf("Expected an error for an unknown program")
+	}
+}
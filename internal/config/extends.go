@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resolveExtends applies program inheritance once all config layers are
+// merged. A program that extends another one inherits every field it does not
+// set itself in any layer, so a parent patched by a higher layer is seen by
+// all of its children.
+func resolveExtends(config *ProgramConfig) error {
+	index := make(map[string]int)
+	for i, program := range config.Programs {
+		index[program.Name] = i
+	}
+
+	resolved := make(map[string]bool)
+	var resolve func(name string, chain []string) error
+	resolve = func(name string, chain []string) error {
+		if resolved[name] {
+			return nil
+		}
+		for i, link := range chain {
+			if link == name {
+				return fmt.Errorf("extends cycle: %s", strings.Join(append(chain[i:], name), " -> "))
+			}
+		}
+
+		i, exists := index[name]
+		if !exists || config.Programs[i].Extends == "" {
+			resolved[name] = true
+			return nil
+		}
+
+		program := config.Programs[i]
+		parentIndex, exists := index[program.Extends]
+		if !exists {
+			return fmt.Errorf("program %s extends unknown program %s", program.Name, program.Extends)
+		}
+		if err := resolve(program.Extends, append(chain, name)); err != nil {
+			return err
+		}
+
+		merged, err := mergeProgram(config.Programs[parentIndex], program)
+		if err != nil {
+			return fmt.Errorf("error extending program %s: %w", program.Name, err)
+		}
+		config.Programs[i] = merged
+		resolved[name] = true
+		return nil
+	}
+
+	for _, program := range config.Programs {
+		if err := resolve(program.Name, nil); err != nil {
+			return fmt.Errorf("validation error: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeConfigs_Extends(t *testing.T) {
+	overrideConfig := &ProgramConfig{
+		Programs: []Program{
+			{Name: "cast", Extends: "forge", Command: "cast", Description: "Cast"},
+			{
+				Name:       "forge",
+				Image:      "ghcr.io/foundry-rs/foundry",
+				Command:    "forge",
+				Serializer: "string",
+				Category:   "Ethereum",
+				Settings:   Settings{Net: "host"},
+			},
+			{Name: "pnpm", Extends: "npm", Command: "pnpm", Tag: "20"},
+		},
+	}
+
+	mergedConfig, err := mergeAndResolve(getProgramConfig(), overrideConfig)
+	if err != nil {
+		t.Fatalf("mergeConfigs failed: %v", err)
+	}
+	if err := validateProgramConfig(mergedConfig); err != nil {
+		t.Fatalf("validation failed: %v", err)
+	}
+
+	programs := map[string]Program{}
+	for _, program := range mergedConfig.Programs {
+		programs[program.Name] = program
+	}
+
+	expectedCast := Program{
+		Name:        "cast",
+		Image:       "ghcr.io/foundry-rs/foundry",
+		Command:     "cast",
+		Serializer:  "string",
+		Description: "Cast",
+		Category:    "Ethereum",
+		Settings:    Settings{Net: "host"},
+		Extends:     "forge",
+	}
+	if diff := cmp.Diff(expectedCast, programs["cast"]); diff != "" {
+		t.Errorf("unexpected cast program (-want +got):\n%s", diff)
+	}
+
+	pnpm := programs["pnpm"]
+	if pnpm.Image != "node" || pnpm.Command != "pnpm" || pnpm.Tag != "20" || pnpm.Category != "Node.js" {
+		t.Errorf("unexpected pnpm program: %+v", pnpm)
+	}
+}
+
+func TestMergeConfigs_ExtendsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		programs []Program
+		message  string
+	}{
+		{
+			name:     "missing parent",
+			programs: []Program{{Name: "cast", Extends: "forge"}},
+			message:  "program cast extends unknown program forge",
+		},
+		{
+			name: "cycle",
+			programs: []Program{
+				{Name: "a", Extends: "b"},
+				{Name: "b", Extends: "c"},
+				{Name: "c", Extends: "a"},
+			},
+			message: "extends cycle: a -> b -> c -> a",
+		},
+		{
+			name:     "self",
+			programs: []Program{{Name: "a", Image: "alpine", Extends: "a"}},
+			message:  "extends cycle: a -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := mergeAndResolve(&ProgramConfig{}, &ProgramConfig{Programs: tt.programs})
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected error to contain %q, got %q", tt.message, err.Error())
+			}
+		})
+	}
+}
+
+func TestMergeConfigs_ExtendsPatchedParent(t *testing.T) {
+	userConfig := &ProgramConfig{
+		Programs: []Program{
+			{Name: "forge", Image: "ghcr.io/foundry-rs/foundry", Command: "forge", Tag: "stable"},
+			{Name: "cast", Extends: "forge", Command: "cast", Tag: "nightly"},
+			{Name: "anvil", Extends: "cast", Command: "anvil"},
+		},
+	}
+	projectConfig := &ProgramConfig{
+		Programs: []Program{
+			{Name: "forge", Image: "ghcr.io/our-org/foundry", Settings: Settings{Net: "host"}},
+		},
+	}
+
+	config := &ProgramConfig{}
+	for _, layer := range []*ProgramConfig{userConfig, projectConfig} {
+		var err error
+		config, err = mergeConfigs(config, layer)
+		if err != nil {
+			t.Fatalf("mergeConfigs failed: %v", err)
+		}
+	}
+	if err := resolveConfig(config); err != nil {
+		t.Fatalf("resolveConfig failed: %v", err)
+	}
+
+	programs := map[string]Program{}
+	for _, program := range config.Programs {
+		programs[program.Name] = program
+	}
+	expected := map[string]Program{
+		"forge": {Name: "forge", Image: "ghcr.io/our-org/foundry", Command: "forge", Tag: "stable", Settings: Settings{Net: "host"}},
+		"cast":  {Name: "cast", Extends: "forge", Image: "ghcr.io/our-org/foundry", Command: "cast", Tag: "nightly", Settings: Settings{Net: "host"}},
+		"anvil": {Name: "anvil", Extends: "cast", Image: "ghcr.io/our-org/foundry", Command: "anvil", Tag: "nightly", Settings: Settings{Net: "host"}},
+	}
+	if diff := cmp.Diff(expected, programs); diff != "" {
+		t.Errorf("unexpected programs (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/shlex"
+)
+
+// Hook matching modes of a program.
+const (
+	HookMatchFirst = "first"
+	HookMatchAll   = "all"
+)
+
+// Matches reports whether the hook applies to the arguments of a program.
+//
+// Command is a list of glob patterns matched against the leading arguments,
+// flags that are not part of the pattern are skipped, so "install" matches
+// "--global install" but not "installer". Only the flags themselves are
+// skipped: the separate value of a flag such as "--prefix /opt" is a positional
+// argument unless the flag is listed in ValueFlags. Regex is matched against
+// the arguments joined with spaces, and every entry of Flags must be passed,
+// either alone or as --flag=value.
+func (h *Hook) Matches(args []string) (bool, error) {
+	if h.Command != "" {
+		patterns, err := shlex.Split(h.Command)
+		if err != nil {
+			return false, fmt.Errorf("error parsing hook command: %w", err)
+		}
+		matched, err := matchTokens(patterns, args, h.ValueFlags)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	if h.Regex != "" {
+		re, err := regexp.Compile(h.Regex)
+		if err != nil {
+			return false, fmt.Errorf("error parsing hook regex: %w", err)
+		}
+		if !re.MatchString(strings.Join(args, " ")) {
+			return false, nil
+		}
+	}
+
+	for _, flag := range h.Flags {
+		if !hasFlag(args, flag) {
+			return false, nil
+		}
+	}
+
+	return h.Command != "" || h.Regex != "" || len(h.Flags) > 0, nil
+}
+
+// matchTokens matches the patterns against the leading arguments, skipping
+// the flags the patterns do not mention along with the values of valueFlags.
+func matchTokens(patterns []string, args []string, valueFlags []string) (bool, error) {
+	next := 0
+	for i := 0; i < len(args); i++ {
+		if next == len(patterns) {
+			break
+		}
+		arg := args[i]
+		matched, err := path.Match(patterns[next], arg)
+		if err != nil {
+			return false, fmt.Errorf("error matching hook pattern %q: %w", patterns[next], err)
+		}
+		if matched {
+			next++
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return false, nil
+		}
+		if contains(valueFlags, arg) {
+			i++
+		}
+	}
+	return next == len(patterns), nil
+}
+
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == flag || strings.HasPrefix(arg, flag+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// Label names the hook in messages.
+func (h *Hook) Label() string {
+	switch {
+	case h.Name != "":
+		return h.Name
+	case h.Command != "":
+		return h.Command
+	case h.Regex != "":
+		return "/" + h.Regex + "/"
+	}
+	return strings.Join(h.Flags, " ")
+}
+
+// MatchHooks returns the hooks matching the arguments: the first one, or all
+// of them in declaration order when hook_match is all.
+func (p *Program) MatchHooks(args []string) ([]Hook, error) {
+	var matched []Hook
+	for _, hook := range p.Hooks {
+		ok, err := hook.Matches(args)
+		if err != nil {
+			return nil, fmt.Errorf("hook %s: %w", hook.Label(), err)
+		}
+		if !ok {
+			continue
+		}
+		matched = append(matched, hook)
+		if p.HookMatch != HookMatchAll {
+			break
+		}
+	}
+	return matched, nil
+}
+
+// WithHooks returns the program with the image, tag, command, serializer and
+// settings of the hooks applied in order, later hooks taking precedence. The
+// before steps of the hooks run after the ones of the program and their after
+// steps before the ones of the program.
+func (p Program) WithHooks(hooks []Hook) (Program, error) {
+	for i, hook := range hooks {
+		if len(hook.Before) > 0 {
+			p.Before = append(append([]Step{}, p.Before...), hook.Before...)
+		}
+		if len(hook.After) > 0 {
+			p.After = append(append([]Step{}, hook.After...), p.After...)
+		}
+		if hook.Image != "" {
+			p.Image = hook.Image
+		}
+		if hook.Tag != "" {
+			p.Tag = hook.Tag
+		}
+		if hook.Run != "" {
+			p.Command = hook.Run
+		}
+		if hook.Serializer != "" {
+			p.Serializer = hook.Serializer
+		}
+
+		// Hook settings already include the program settings, see semanticMerge
+		if i == 0 {
+			p.Settings = hook.Settings
+			continue
+		}
+		settings, err := MergeSettings(p.Settings, hook.Settings)
+		if err != nil {
+			return p, fmt.Errorf("error merging settings of hook %s: %w", hook.Label(), err)
+		}
+		p.Settings = settings
+	}
+	return p, nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHookMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		hook     Hook
+		args     []string
+		expected bool
+	}{
+		{name: "token", hook: Hook{Command: "install"}, args: []string{"install", "jq"}, expected: true},
+		{name: "token prefix", hook: Hook{Command: "install"}, args: []string{"installer"}},
+		{name: "flag before token", hook: Hook{Command: "install"}, args: []string{"--global", "install", "jq"}, expected: true},
+		{name: "several tokens", hook: Hook{Command: "run build"}, args: []string{"run", "build", "--prod"}, expected: true},
+		{name: "other subcommand", hook: Hook{Command: "run build"}, args: []string{"run", "test"}},
+		{name: "glob", hook: Hook{Command: "run test*"}, args: []string{"run", "test:unit"}, expected: true},
+		{name: "flag value", hook: Hook{Command: "install"}, args: []string{"--prefix", "/opt", "install"}},
+		{name: "value flag", hook: Hook{Command: "install", ValueFlags: []string{"--prefix"}}, args: []string{"--prefix", "/opt", "install"}, expected: true},
+		{name: "value flag inline", hook: Hook{Command: "install", ValueFlags: []string{"--prefix"}}, args: []string{"--prefix=/opt", "install"}, expected: true},
+		{name: "flag in pattern", hook: Hook{Command: "-e"}, args: []string{"-e", "1+1"}, expected: true},
+		{name: "regex", hook: Hook{Regex: "^build --sizes"}, args: []string{"build", "--sizes"}, expected: true},
+		{name: "regex mismatch", hook: Hook{Regex: "^test"}, args: []string{"build"}},
+		{name: "flag", hook: Hook{Flags: []string{"--global"}}, args: []string{"install", "--global"}, expected: true},
+		{name: "flag with value", hook: Hook{Flags: []string{"--prefix"}}, args: []string{"--prefix=/opt", "install"}, expected: true},
+		{name: "flag after separator", hook: Hook{Flags: []string{"--global"}}, args: []string{"exec", "--", "--global"}},
+		{name: "all criteria", hook: Hook{Command: "install", Flags: []string{"-g"}}, args: []string{"install", "jq"}},
+		{name: "no criteria", hook: Hook{}, args: []string{"install"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matched, err := test.hook.Matches(test.args)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if matched != test.expected {
+				t.Errorf("Matches(%v) = %v, expected %v", test.args, matched, test.expected)
+			}
+		})
+	}
+}
+
+func TestProgramWithHooks(t *testing.T) {
+	program := Program{
+		Name:    "npm",
+		Image:   "node",
+		Command: "npm",
+		Hooks: []Hook{
+			{Command: "install", Image: "node-install", Settings: Settings{Net: "bridge", IgnorePaths: []string{".env"}}},
+			{Flags: []string{"-g"}, Tag: "20", Run: "pnpm", Settings: Settings{IgnorePaths: []string{"secrets"}}},
+		},
+	}
+	args := []string{"install", "-g", "jq"}
+
+	first, err := program.MatchHooks(args)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(first) != 1 || first[0].Command != "install" {
+		t.Fatalf("Expected the first matching hook only, got %v", first)
+	}
+
+	program.HookMatch = HookMatchAll
+	all, err := program.MatchHooks(args)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("Expected every matching hook, got %v", all)
+	}
+
+	applied, err := program.WithHooks(all)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := program
+	expected.Image = "node-install"
+	expected.Tag = "20"
+	expected.Command = "pnpm"
+	expected.Settings = Settings{Net: "bridge", IgnorePaths: []string{"secrets", ".env"}}
+	if diff := cmp.Diff(expected, applied); diff != "" {
+		t.Errorf("Unexpected program (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// configLoader loads config files together with the files they include and
+// keeps them in merge order: the includes of a file come before the file itself.
+type configLoader struct {
+	files    []string
+	layers   []string
+	configs  []*ProgramConfig
+	warnings ValidationErrors
+	chain    []string
+	raw      bool
+	// layer names the config directory being loaded, files pulled in with
+	// include belong to the layer of the including file
+	layer string
+}
+
+// load reads the config at filePath and, recursively, its includes.
+// A file that is reached again through its own includes is reported as a cycle.
+func (l *configLoader) load(filePath string) error {
+	for i, path := range l.chain {
+		if path == filePath {
+			chain := append(append([]string{}, l.chain[i:]...), filePath)
+			return fmt.Errorf("include cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	for _, path := range l.files {
+		if path == filePath {
+			return nil
+		}
+	}
+
+	config, unknownKeys, err := readConfigFile(filePath, l.raw)
+	if err != nil {
+		return err
+	}
+	l.warnings = append(l.warnings, unknownKeys...)
+
+	includes, err := resolveIncludes(filePath, config.Include)
+	if err != nil {
+		return err
+	}
+
+	l.chain = append(l.chain, filePath)
+	for _, include := range includes {
+		if err := l.load(include); err != nil {
+			return err
+		}
+	}
+	l.chain = l.chain[:len(l.chain)-1]
+
+	l.files = append(l.files, filePath)
+	l.layers = append(l.layers, l.layer)
+	l.configs = append(l.configs, config)
+	return nil
+}
+
+// printWarnings reports the problems that did not prevent the configs from loading.
+func (l *configLoader) printWarnings() {
+	for _, warning := range l.warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+}
+
+// loadDir loads config.yaml and then the conf.d/*.yaml fragments of a .cubx directory.
+func (l *configLoader) loadDir(cubxDir string) error {
+	files, err := configDirFiles(cubxDir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := l.load(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configDirFiles lists config.yaml and the conf.d/*.yaml fragments of a .cubx
+// directory in the order they are merged.
+func configDirFiles(cubxDir string) ([]string, error) {
+	var files []string
+	configPath := filepath.Join(cubxDir, configFileName)
+	if _, err := os.Stat(configPath); err == nil {
+		files = append(files, configPath)
+	}
+
+	fragments, err := filepath.Glob(filepath.Join(cubxDir, "conf.d", "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(fragments)
+	return append(files, fragments...), nil
+}
+
+// resolveIncludes expands include entries relative to the directory of the
+// including file. Globs are expanded in lexical order and may match nothing,
+// plain paths must exist.
+func resolveIncludes(filePath string, includes []string) ([]string, error) {
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("error converting path to absolute: %w", err)
+	}
+
+	var files []string
+	for _, include := range includes {
+		pattern := include
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		if !strings.ContainsAny(include, "*?[") {
+			if _, err := os.Stat(pattern); err != nil {
+				return nil, fmt.Errorf("include %s in %s: %w", include, filePath, err)
+			}
+			files = append(files, pattern)
+			continue
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include %s in %s: %w", include, filePath, err)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
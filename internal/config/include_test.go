@@ -0,0 +1,131 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) {
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func TestConfigLoaderIncludesAndFragments(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	cubxDir := filepath.Join(tempDir, ".cubx")
+	configPath := filepath.Join(cubxDir, "config.yaml")
+	ethereumPath := filepath.Join(tempDir, "shared", "ethereum.yaml")
+	networkPath := filepath.Join(tempDir, "shared", "network.yaml")
+	fragmentA := filepath.Join(cubxDir, "conf.d", "10-a.yaml")
+	fragmentB := filepath.Join(cubxDir, "conf.d", "20-b.yaml")
+
+	writeConfigFiles(t, map[string]string{
+		configPath: `
+include: ["../shared/*.yaml"]
+programs:
+  - name: forge
+    image: ghcr.io/foundry-rs/foundry
+    tag: nightly
+`,
+		ethereumPath: `
+programs:
+  - name: forge
+    image: ghcr.io/foundry-rs/foundry
+    tag: stable
+`,
+		networkPath: `
+programs:
+  - name: curl
+    image: curlimages/curl
+`,
+		fragmentB: `
+programs:
+  - name: jq
+    image: ghcr.io/jqlang/jq
+    tag: "1.7"
+`,
+		fragmentA: `
+programs:
+  - name: jq
+    image: ghcr.io/jqlang/jq
+    tag: "1.6"
+`,
+	})
+
+	loader := &configLoader{}
+	if err := loader.loadDir(cubxDir); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	expectedFiles := []string{ethereumPath, networkPath, configPath, fragmentA, fragmentB}
+	if diff := cmp.Diff(expectedFiles, loader.files); diff != "" {
+		t.Fatalf("Unexpected load order (-want +got):\n%s", diff)
+	}
+
+	config := &ProgramConfig{}
+	for _, fileConfig := range loader.configs {
+		var err error
+		config, err = mergeConfigs(config, fileConfig)
+		if err != nil {
+			t.Fatalf("mergeConfigs failed: %v", err)
+		}
+	}
+
+	tags := map[string]string{}
+	for _, program := range config.Programs {
+		tags[program.Name] = program.Tag
+	}
+	expectedTags := map[string]string{"forge": "nightly", "curl": "", "jq": "1.7"}
+	if diff := cmp.Diff(expectedTags, tags); diff != "" {
+		t.Fatalf("Unexpected programs (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfigLoaderIncludeCycle(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	basePath := filepath.Join(tempDir, "base.yaml")
+	writeConfigFiles(t, map[string]string{
+		configPath: "include: [base.yaml]\n",
+		basePath:   "include: [config.yaml]\n",
+	})
+
+	loader := &configLoader{}
+	err := loader.load(configPath)
+	if err == nil {
+		t.Fatal("Expected include cycle error, got nil")
+	}
+	expectedChain := strings.Join([]string{configPath, basePath, configPath}, " -> ")
+	if !strings.Contains(err.Error(), expectedChain) {
+		t.Errorf("Expected error to contain the chain %q, got %q", expectedChain, err.Error())
+	}
+}
+
+func TestConfigLoaderMissingInclude(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	writeConfigFiles(t, map[string]string{
+		configPath: "include: [missing.yaml, \"optional/*.yaml\"]\n",
+	})
+
+	loader := &configLoader{}
+	if err := loader.load(configPath); err == nil {
+		t.Fatal("Expected error for a missing include, got nil")
+	}
+}
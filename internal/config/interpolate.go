@@ -0,0 +1,192 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// interpolationSkipFields are identifiers that are never expanded.
+var interpolationSkipFields = map[string]bool{
+	"Name":    true,
+	"Extends": true,
+}
+
+// ProjectRoot returns the root of the git repository containing the working
+// directory, or the working directory itself outside of a repository.
+func ProjectRoot() (string, error) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("getting current directory: %w", err)
+	}
+	for dir := pwd; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return pwd, nil
+		}
+		dir = parent
+	}
+}
+
+// interpolator expands ${HOME}, ${PWD}, ${PROJECT_ROOT}, ${env:NAME} and
+// ${env:NAME:-default} in config values. $${ is kept as a literal ${.
+type interpolator struct {
+	vars map[string]string
+}
+
+func newInterpolator() (*interpolator, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting current directory: %w", err)
+	}
+	projectRoot, err := ProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	return &interpolator{vars: map[string]string{
+		"HOME":         home,
+		"PWD":          pwd,
+		"PROJECT_ROOT": projectRoot,
+	}}, nil
+}
+
+// expand replaces the variables of a single value.
+func (i *interpolator) expand(value string) (string, error) {
+	var sb strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start == -1 {
+			sb.WriteString(value)
+			return sb.String(), nil
+		}
+		if start > 0 && value[start-1] == '$' {
+			sb.WriteString(value[:start-1] + "${")
+			value = value[start+2:]
+			continue
+		}
+		end := strings.Index(value[start:], "}")
+		if end == -1 {
+			return "", fmt.Errorf("unterminated variable in %q", value)
+		}
+		resolved, err := i.resolve(value[start+2 : start+end])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(value[:start] + resolved)
+		value = value[start+end+1:]
+	}
+}
+
+func (i *interpolator) resolve(name string) (string, error) {
+	if envName, ok := strings.CutPrefix(name, "env:"); ok {
+		envName, defaultValue, hasDefault := strings.Cut(envName, ":-")
+		if value, ok := os.LookupEnv(envName); ok {
+			return value, nil
+		}
+		if hasDefault {
+			return defaultValue, nil
+		}
+		return "", fmt.Errorf("undefined variable ${%s}", name)
+	}
+	if value, ok := i.vars[name]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown variable ${%s}, use ${env:%s} for environment variables", name, name)
+}
+
+// expansion collects the results of expanding a config file.
+type expansion struct {
+	file string
+	errs ValidationErrors
+	// skipped are the validator namespaces of the blocks whose when condition
+	// does not match
+	skipped []string
+}
+
+// expandConfig expands the variables in every string field of a config file
+// and returns the namespaces of the blocks skipped by their when condition.
+// The condition of a block is expanded and evaluated first, and the rest of a
+// skipped block is left as is, since it is dropped when the layers are merged.
+func (i *interpolator) expandConfig(config *ProgramConfig, file string) ([]string, error) {
+	e := &expansion{file: file}
+	i.expandValue(reflect.ValueOf(config).Elem(), "", "ProgramConfig", e)
+	if len(e.errs) > 0 {
+		return nil, e.errs
+	}
+	return e.skipped, nil
+}
+
+// expandValue expands v, path is the config path used in messages and ns the
+// validator namespace of v.
+func (i *interpolator) expandValue(v reflect.Value, path string, ns string, e *expansion) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			i.expandValue(v.Elem(), path, ns, e)
+		}
+	case reflect.String:
+		expanded, err := i.expand(v.String())
+		if err != nil {
+			e.errs = append(e.errs, &ValidationError{File: e.file, Path: path, Message: err.Error()})
+			return
+		}
+		if v.CanSet() {
+			v.SetString(expanded)
+		}
+	case reflect.Struct:
+		if when := v.FieldByName("When"); when.IsValid() && when.Type() == reflect.TypeOf((*Condition)(nil)) && !when.IsNil() {
+			whenPath := joinPath(path, "when")
+			errCount := len(e.errs)
+			i.expandValue(when, whenPath, ns+".When", e)
+			if len(e.errs) > errCount {
+				return
+			}
+			matched, _, err := when.Interface().(*Condition).Matches()
+			if err != nil {
+				e.errs = append(e.errs, &ValidationError{File: e.file, Path: whenPath, Message: err.Error()})
+				return
+			}
+			if !matched {
+				e.skipped = append(e.skipped, ns)
+				return
+			}
+		}
+
+		t := v.Type()
+		for j := 0; j < t.NumField(); j++ {
+			field := t.Field(j)
+			if !field.IsExported() || interpolationSkipFields[field.Name] {
+				continue
+			}
+			i.expandValue(v.Field(j), joinPath(path, yamlKey(field)), ns+"."+field.Name, e)
+		}
+	case reflect.Slice, reflect.Array:
+		for j := 0; j < v.Len(); j++ {
+			label := strconv.Itoa(j)
+			if item := reflect.Indirect(v.Index(j)); item.Kind() == reflect.Struct {
+				if name := item.FieldByName("Name"); name.IsValid() && name.Kind() == reflect.String && name.String() != "" {
+					label = name.String()
+				}
+			}
+			i.expandValue(v.Index(j), fmt.Sprintf("%s[%s]", path, label), fmt.Sprintf("%s[%d]", ns, j), e)
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			// Map values are not addressable, expand a copy and store it back
+			item := reflect.New(v.Type().Elem()).Elem()
+			item.Set(v.MapIndex(key))
+			i.expandValue(item, joinPath(path, fmt.Sprint(key.Interface())), fmt.Sprintf("%s[%v]", ns, key.Interface()), e)
+			v.SetMapIndex(key, item)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterpolatorExpand(t *testing.T) {
+	t.Setenv("CUBX_TEST_RPC", "http://rpc")
+	i := &interpolator{vars: map[string]string{
+		"HOME":         "/home/user",
+		"PWD":          "/work/project/src",
+		"PROJECT_ROOT": "/work/project",
+	}}
+
+	tests := []struct {
+		value    string
+		expected string
+		err      string
+	}{
+		{value: "${HOME}/.foundry:/root/.foundry", expected: "/home/user/.foundry:/root/.foundry"},
+		{value: "${PROJECT_ROOT}:${PWD}", expected: "/work/project:/work/project/src"},
+		{value: "RPC=${env:CUBX_TEST_RPC}", expected: "RPC=http://rpc"},
+		{value: "${env:CUBX_TEST_UNSET:-fallback}", expected: "fallback"},
+		{value: "${env:CUBX_TEST_UNSET:-}", expected: ""},
+		{value: "echo $${HOME}", expected: "echo ${HOME}"},
+		{value: "no variables", expected: "no variables"},
+		{value: "${env:CUBX_TEST_UNSET}", err: "undefined variable ${env:CUBX_TEST_UNSET}"},
+		{value: "${USER}", err: "unknown variable ${USER}"},
+		{value: "${HOME", err: "unterminated variable"},
+	}
+
+	for _, test := range tests {
+		actual, err := i.expand(test.value)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expand(%q): expected error containing %q, got %v", test.value, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expand(%q): unexpected error: %v", test.value, err)
+			continue
+		}
+		if actual != test.expected {
+			t.Errorf("expand(%q) = %q, expected %q", test.value, actual, test.expected)
+		}
+	}
+}
+
+func TestLoadConfigFileInterpolation(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+	t.Setenv("CUBX_TEST_TAG", "1.7")
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	writeConfigFiles(t, map[string]string{
+		configPath: `
+programs:
+  - name: jq
+    image: ghcr.io/jqlang/jq
+    tag: ${env:CUBX_TEST_TAG}
+    settings:
+      mounts:
+        - ${PWD}/data:/data
+`,
+	})
+
+	config, err := loadConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	program := config.Programs[0]
+	if program.Tag != "1.7" {
+		t.Errorf("Expected tag 1.7, got %q", program.Tag)
+	}
+	pwd, _ := os.Getwd()
+	if expected := (Mount{Source: pwd + "/data", Target: "/data"}); len(program.Settings.Mounts) != 1 || !reflect.DeepEqual(program.Settings.Mounts[0], expected) {
+		t.Errorf("Expected mount %v, got %v", expected, program.Settings.Mounts)
+	}
+
+	raw, _, err := readConfigFile(configPath, true)
+	if err != nil {
+		t.Fatalf("Failed to read raw config: %v", err)
+	}
+	if raw.Programs[0].Tag != "${env:CUBX_TEST_TAG}" {
+		t.Errorf("Expected raw tag to be unexpanded, got %q", raw.Programs[0].Tag)
+	}
+}
+
+func TestLoadConfigFileUndefinedVariable(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	writeConfigFiles(t, map[string]string{
+		configPath: `
+programs:
+  - name: jq
+    image: ghcr.io/jqlang/jq
+    settings:
+      env:
+        - TOKEN=${env:CUBX_TEST_UNDEFINED}
+`,
+	})
+
+	_, err := loadConfigFile(configPath)
+	var validationErrors ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		t.Fatalf("Expected validation errors, got %v", err)
+	}
+	if path := validationErrors[0].Path; path != "programs[jq].settings.env[0]" {
+		t.Errorf("Expected error at programs[jq].settings.env[0], got %q", path)
+	}
+}
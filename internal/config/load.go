@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 
 	"dario.cat/mergo"
@@ -23,17 +24,33 @@ func cloneProgramConfig(config *ProgramConfig) (*ProgramConfig, error) {
 	return &clonedConfig, nil
 }
 
-func mergePrograms(baseConfig, overrideConfig *ProgramConfig) *[]Program {
-	var allPrograms []Program
+// mergePrograms merges the programs of two config layers by name. A program of
+// the override layer patches only the fields it sets, unless it asks to replace
+// the lower definition entirely or to disable the program.
+func mergePrograms(baseConfig, overrideConfig *ProgramConfig) (*[]Program, error) {
 	programMap := make(map[string]Program)
 
-	allPrograms = append(allPrograms, baseConfig.Programs...)
-	allPrograms = append(allPrograms, overrideConfig.Programs...)
-
-	for _, program := range allPrograms {
+	for _, program := range baseConfig.Programs {
 		programMap[program.Name] = program
 	}
 
+	for _, program := range overrideConfig.Programs {
+		base, exists := programMap[program.Name]
+		switch {
+		case program.Disabled:
+			delete(programMap, program.Name)
+		case !exists || program.Replace:
+			program.Replace = false
+			programMap[program.Name] = program
+		default:
+			merged, err := mergeProgram(base, program)
+			if err != nil {
+				return nil, fmt.Errorf("error merging program %s: %w", program.Name, err)
+			}
+			programMap[program.Name] = merged
+		}
+	}
+
 	var mergedPrograms []Program
 	for _, program := range programMap {
 		mergedPrograms = append(mergedPrograms, program)
@@ -43,100 +60,367 @@ func mergePrograms(baseConfig, overrideConfig *ProgramConfig) *[]Program {
 		return mergedPrograms[i].Name < mergedPrograms[j].Name
 	})
 
-	return &mergedPrograms
+	return &mergedPrograms, nil
 }
 
+// mergeProgram overrides the fields of base that are set in override.
+func mergeProgram(base, override Program) (Program, error) {
+	merged := Program{}
+	data, err := json.Marshal(base)
+	if err != nil {
+		return base, err
+	}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return base, err
+	}
+	if err := mergo.Merge(&merged, override, mergo.WithOverride); err != nil {
+		return base, err
+	}
+	merged.Replace = false
+	return merged, nil
+}
+
+// mergeConfigs merges a config layer into the lower ones. The programs are left
+// unresolved, see resolveConfig.
 func mergeConfigs(baseConfig, overrideConfig *ProgramConfig) (*ProgramConfig, error) {
 	clonedConfig, err := cloneProgramConfig(baseConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	clonedConfig.Programs = *mergePrograms(clonedConfig, overrideConfig)
+	// The merged programs share their hooks with the override layer, clone it
+	// so that semanticMerge does not write into the loaded config
+	overrideConfig, err = cloneProgramConfig(overrideConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := applyConditions(overrideConfig); err != nil {
+		return nil, err
+	}
+
+	mergedPrograms, err := mergePrograms(clonedConfig, overrideConfig)
+	if err != nil {
+		return nil, err
+	}
+	clonedConfig.Programs = *mergedPrograms
 
 	if err := mergo.Merge(&clonedConfig.Settings, &overrideConfig.Settings, mergo.WithOverride); err != nil {
 		return nil, err
 	}
 
-	if err := semanticMerge(clonedConfig); err != nil {
+	// Rules of later layers apply after the ones of lower layers
+	clonedConfig.Rules = append(clonedConfig.Rules, overrideConfig.Rules...)
+
+	clonedConfig.Profiles, err = mergeProfiles(clonedConfig.Profiles, overrideConfig.Profiles)
+	if err != nil {
 		return nil, err
 	}
+	if overrideConfig.DefaultProfile != "" {
+		clonedConfig.DefaultProfile = overrideConfig.DefaultProfile
+	}
 
 	return clonedConfig, nil
 }
 
+// resolveConfig applies program inheritance and then the global settings and
+// rules to the programs and hooks. It runs once on the merged layers, so that
+// every layer can patch a parent or the global settings.
+func resolveConfig(config *ProgramConfig) error {
+	if err := resolveExtends(config); err != nil {
+		return err
+	}
+	return semanticMerge(config)
+}
+
 func loadConfigFile(filePath string) (*ProgramConfig, error) {
+	config, _, err := readConfigFile(filePath, false)
+	return config, err
+}
+
+// readConfigFile parses, interpolates and validates a config file. Unknown keys
+// are returned separately because they do not prevent the file from being used.
+// A raw config is neither interpolated nor validated.
+func readConfigFile(filePath string, raw bool) (*ProgramConfig, ValidationErrors, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return &ProgramConfig{}, nil
+			return &ProgramConfig{}, nil, nil
 		}
-		return nil, err
+		return nil, nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, fmt.Errorf("unable to parse file %s: %w", displayPath(filePath), err)
 	}
 
 	var config ProgramConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("unable to decode file %s into struct: %w", filePath, err)
+	if err := root.Decode(&config); err != nil {
+		return nil, nil, fmt.Errorf("unable to decode file %s into struct: %w", displayPath(filePath), err)
 	}
 
-	// Validate the configuration structure
-	if err := validateProgramConfig(&config); err != nil {
-		return nil, fmt.Errorf("validation error: %w", err)
+	unknownKeys := checkUnknownKeys(&root, reflect.TypeOf(config), "", displayPath(filePath))
+
+	if !raw {
+		interpolator, err := newInterpolator()
+		if err != nil {
+			return nil, unknownKeys, err
+		}
+		skipped, err := interpolator.expandConfig(&config, displayPath(filePath))
+		if err != nil {
+			return nil, unknownKeys, err
+		}
+
+		// Validate the configuration structure
+		if err := validateConfigFile(&config, &root, displayPath(filePath), skipped); err != nil {
+			return nil, unknownKeys, err
+		}
+
+		if err := resolveEnvFiles(&config, filePath); err != nil {
+			return nil, unknownKeys, err
+		}
 	}
 
-	return &config, nil
+	if err := resolveDockerfiles(&config, filePath); err != nil {
+		return nil, unknownKeys, err
+	}
+
+	return &config, unknownKeys, nil
 }
 
-func LoadConfig(withDefaults bool) (*ProgramConfig, []string, error) {
-	configFileName := "config.yaml"
-	var loadedConfigs []string
+// ValidateFile checks a config file without loading it, including the keys
+// that are not part of the config format.
+func ValidateFile(filePath string) error {
+	if _, err := os.Stat(filePath); err != nil {
+		return err
+	}
+
+	_, unknownKeys, err := readConfigFile(filePath, false)
+	if err == nil && len(unknownKeys) == 0 {
+		return nil
+	}
+
+	errs := append(ValidationErrors{}, unknownKeys...)
+	if validationErrors, ok := err.(ValidationErrors); ok {
+		errs = append(errs, validationErrors...)
+	} else if err != nil {
+		return err
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Line < errs[j].Line
+	})
+	return errs
+}
+
+const configFileName = "config.yaml"
+
+// findConfigDirs returns the home .cubx directory followed by the project
+// .cubx directories, in merge order.
+func findConfigDirs() ([]string, error) {
 	pwd, err := os.Getwd()
 	if err != nil {
-		return nil, nil, fmt.Errorf("getting current directory: %w", err)
+		return nil, fmt.Errorf("getting current directory: %w", err)
 	}
-	// Load current directory config
-	currentDirConfigPath := filepath.Join(pwd, ".cubx", configFileName)
-	currentConfig, err := loadConfigFile(currentDirConfigPath)
+
+	home, err := os.UserHomeDir()
 	if err != nil {
-		return nil, nil, err
+		return nil, fmt.Errorf("error getting home directory: %w", err)
 	}
+	homeConfigDir := filepath.Join(home, ".cubx")
 
-	if withDefaults {
-		currentConfig, err = mergeConfigs(getProgramConfig(), currentConfig)
+	configDirs := []string{homeConfigDir}
+	for _, configDir := range findProjectConfigDirs(pwd) {
+		if configDir != homeConfigDir {
+			configDirs = append(configDirs, configDir)
+		}
+	}
+	return configDirs, nil
+}
+
+// ConfigFiles returns the config.yaml and conf.d files cubx reads, in merge
+// order. Files pulled in with include are not listed.
+func ConfigFiles() ([]string, error) {
+	configDirs, err := findConfigDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, configDir := range configDirs {
+		dirFiles, err := configDirFiles(configDir)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
+		files = append(files, dirFiles...)
 	}
+	return files, nil
+}
+
+// findProjectConfigDirs walks up from dir to the git root or the filesystem root
+// and returns the .cubx directories found on the way, outermost first.
+func findProjectConfigDirs(dir string) []string {
+	var dirs []string
+	for {
+		cubxDir := filepath.Join(dir, ".cubx")
+		if info, err := os.Stat(cubxDir); err == nil && info.IsDir() {
+			dirs = append([]string{cubxDir}, dirs...)
+		}
+
+		// Stop at the root of the repository
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
 
-	if _, err := os.Stat(currentDirConfigPath); err == nil {
-		loadedConfigs = append(loadedConfigs, currentDirConfigPath)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
 	}
+	return dirs
+}
 
-	// Load home directory config
-	home, err := os.UserHomeDir()
+// LoadConfig loads the home config and every project config found between the
+// working directory and the git root, and merges them from the outermost to the
+// innermost on top of the defaults. Each config is preceded by the files it
+// includes and followed by its conf.d fragments. The returned paths follow the
+// merge order.
+func LoadConfig(withDefaults bool) (*ProgramConfig, []string, error) {
+	return loadConfig(withDefaults, false)
+}
+
+// LoadRawConfig loads the configuration like LoadConfig but keeps the
+// ${...} variables of the config files unexpanded.
+func LoadRawConfig(withDefaults bool) (*ProgramConfig, []string, error) {
+	return loadConfig(withDefaults, true)
+}
+
+func loadConfig(withDefaults bool, raw bool) (*ProgramConfig, []string, error) {
+	loader, finalConfig, err := loadLayers(withDefaults, raw)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error getting home directory: %w", err)
+		return nil, nil, err
+	}
+
+	if raw {
+		return finalConfig, loader.files, nil
 	}
-	homeConfigPath := filepath.Join(home, ".cubx", configFileName)
-	homeConfig, err := loadConfigFile(homeConfigPath)
+
+	if err := validateProgramConfig(finalConfig); err != nil {
+		return nil, nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	preparedConfig, err := configPreprocessing(finalConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return preparedConfig, loader.files, nil
+}
+
+// Config layers in merge order.
+const (
+	layerDefaults = "defaults"
+	layerUser     = "user"
+	layerProject  = "project"
+)
+
+// loadLayers loads the config files and merges them on top of the defaults.
+// The returned loader keeps the files in merge order together with their layer.
+func loadLayers(withDefaults bool, raw bool) (*configLoader, *ProgramConfig, error) {
+	configDirs, err := findConfigDirs()
 	if err != nil {
 		return nil, nil, err
 	}
-	if _, err := os.Stat(homeConfigPath); err == nil && homeConfigPath != currentDirConfigPath {
-		loadedConfigs = append(loadedConfigs, homeConfigPath)
+
+	loader := &configLoader{raw: raw}
+	if !raw {
+		// The raw config is only loaded next to the expanded one, warn once
+		defer loader.printWarnings()
+	}
+	for i, configDir := range configDirs {
+		// findConfigDirs always starts with the home directory
+		loader.layer = layerProject
+		if i == 0 {
+			loader.layer = layerUser
+		}
+		if err := loader.loadDir(configDir); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	finalConfig := &ProgramConfig{}
+	if withDefaults {
+		finalConfig = getProgramConfig()
 	}
 
 	// Merge the configurations
-	finalConfig, err := mergeConfigs(homeConfig, currentConfig)
-	if err != nil {
+	for _, fileConfig := range loader.configs {
+		finalConfig, err = mergeConfigs(finalConfig, fileConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := resolveConfig(finalConfig); err != nil {
 		return nil, nil, err
 	}
+	return loader, finalConfig, nil
+}
 
-	preparedConfig, err := configPreprocessing(finalConfig, loadedConfigs)
+// resolveDockerfiles makes the Dockerfile paths of the programs relative to the
+// directory of the config file that declared them.
+func resolveDockerfiles(config *ProgramConfig, filePath string) error {
+	dir, err := filepath.Abs(filepath.Dir(filePath))
 	if err != nil {
-		return nil, nil, err
+		return fmt.Errorf("error converting path to absolute: %w", err)
 	}
-	return preparedConfig, loadedConfigs, nil
+	for index := range config.Programs {
+		program := &config.Programs[index]
+		if program.Dockerfile == "" || filepath.IsAbs(program.Dockerfile) {
+			continue
+		}
+		if findDockerfileInDirectory(dir, program.Dockerfile) {
+			program.Dockerfile = filepath.Join(dir, program.Dockerfile)
+		}
+	}
+	return nil
+}
+
+// resolveEnvFiles makes the relative env_file paths of every settings block
+// relative to the directory of the config file that declared them, so that
+// they do not depend on where in the project cubx runs.
+func resolveEnvFiles(config *ProgramConfig, filePath string) error {
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return fmt.Errorf("error converting path to absolute: %w", err)
+	}
+	resolve := func(settings *Settings) {
+		for i, envFile := range settings.EnvFile {
+			if !filepath.IsAbs(envFile) {
+				settings.EnvFile[i] = filepath.Join(dir, envFile)
+			}
+		}
+	}
+
+	resolve(&config.Settings)
+	for i := range config.Rules {
+		resolve(&config.Rules[i].Settings)
+	}
+	for name, profile := range config.Profiles {
+		resolve(&profile.Settings)
+		for program, settings := range profile.Programs {
+			resolve(&settings)
+			profile.Programs[program] = settings
+		}
+		config.Profiles[name] = profile
+	}
+	for i := range config.Programs {
+		program := &config.Programs[i]
+		resolve(&program.Settings)
+		for j := range program.Hooks {
+			resolve(&program.Hooks[j].Settings)
+		}
+	}
+	return nil
 }
 
 func findDockerfileInDirectory(dir, dockerfileName string) bool {
@@ -149,34 +433,13 @@ func findDockerfileInDirectory(dir, dockerfileName string) bool {
 	return false
 }
 
-func configPreprocessing(finalConfig *ProgramConfig, loadedConfigs []string) (*ProgramConfig, error) {
-	foundDockerfile := make(map[string]bool)
-
-	// Reversing the paths to configuration files in reverse order
-	for i := len(loadedConfigs) - 1; i >= 0; i-- {
-		filePath := loadedConfigs[i]
-		dir := filepath.Dir(filePath)
-
-		// Searching all programs in the final configuration
-		for index := range finalConfig.Programs {
-			program := &finalConfig.Programs[index] // We get a link to the program
-			if program.Dockerfile == "" {
-				continue // Skip programs without a specified Dockerfile path
-			}
-
-			// Search for Dockerfile in the current directory
-			if findDockerfileInDirectory(dir, program.Dockerfile) {
-				// fmt.Printf("Dockerfile '%s' found in directory: %s\n", program.Dockerfile, dir)
-				program.Dockerfile = filepath.Join(dir, program.Dockerfile) // Changing the path in the original slice
-				foundDockerfile[program.Dockerfile] = true
-			}
-		}
-		// fmt.Println("Processed file and directory:", filePath, dir)
-	}
-
+func configPreprocessing(finalConfig *ProgramConfig) (*ProgramConfig, error) {
 	// Checking for the presence of a Dockerfile for each program that requires it
 	for _, program := range finalConfig.Programs {
-		if program.Dockerfile != "" && !foundDockerfile[program.Dockerfile] {
+		if program.Dockerfile == "" {
+			continue
+		}
+		if _, err := os.Stat(program.Dockerfile); err != nil || !filepath.IsAbs(program.Dockerfile) {
 			return nil, fmt.Errorf("dockerfile not found for program: %s", program.Dockerfile)
 		}
 	}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -19,6 +20,20 @@ func setupTempDir(t *testing.T) (string, func()) {
 	return tempDir, cleanup
 }
 
+// assertLoadError writes a config file with the content and checks that
+// loading it fails with an error containing want.
+func assertLoadError(t *testing.T, content, want string) {
+	t.Helper()
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	writeConfigFiles(t, map[string]string{configPath: content})
+	if _, err := loadConfigFile(configPath); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected an error containing %q, got %v", want, err)
+	}
+}
+
 func TestLoadValidConfig(t *testing.T) {
 	tempDir, cleanup := setupTempDir(t)
 	defer cleanup()
@@ -217,3 +232,165 @@ programs:
 		t.Errorf("Expected Program handler 'string', got '%s'", cmd2.Serializer)
 	}
 }
+
+func TestLoadConfigInvalidTTY(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	configContent := []byte(`
+programs:
+  - name: jq
+    image: ghcr.io/jqlang/jq
+    tty: sometimes
+`)
+	if err := os.WriteFile(configPath, configContent, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	if _, err := loadConfigFile(configPath); err == nil {
+		t.Fatal("Expected error for invalid tty mode, got nil")
+	}
+}
+
+func TestLoadConfigWalksUpToGitRoot(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	repoDir := filepath.Join(tempDir, "repo")
+	packageDir := filepath.Join(repoDir, "packages", "api")
+	for _, dir := range []string{
+		filepath.Join(tempDir, ".cubx"),
+		filepath.Join(repoDir, ".git"),
+		filepath.Join(repoDir, ".cubx"),
+		filepath.Join(packageDir, ".cubx"),
+		filepath.Join(tempDir, "home"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+
+	// Above the git root, must not be loaded
+	outsideConfigPath := filepath.Join(tempDir, ".cubx", "config.yaml")
+	repoConfigPath := filepath.Join(repoDir, ".cubx", "config.yaml")
+	packageConfigPath := filepath.Join(packageDir, ".cubx", "config.yaml")
+	files := map[string]string{
+		outsideConfigPath: `
+programs:
+  - name: outside
+    image: outside
+`,
+		repoConfigPath: `
+programs:
+  - name: shared
+    image: repo-image
+    dockerfile: Dockerfile
+  - name: repo
+    image: repo
+`,
+		filepath.Join(repoDir, ".cubx", "Dockerfile"): "FROM alpine\n",
+		packageConfigPath: `
+programs:
+  - name: shared
+    image: package-image
+    dockerfile: Dockerfile
+`,
+		filepath.Join(packageDir, ".cubx", "Dockerfile"): "FROM alpine\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	homeDir := os.Getenv("HOME")
+	os.Setenv("HOME", filepath.Join(tempDir, "home"))
+	defer os.Setenv("HOME", homeDir)
+
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	if err := os.Chdir(packageDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	config, loadedConfigs, err := LoadConfig(false)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	expectedLoadedConfigs := []string{repoConfigPath, packageConfigPath}
+	if diff := cmp.Diff(expectedLoadedConfigs, loadedConfigs); diff != "" {
+		t.Fatalf("Unexpected loaded configs (-want +got):\n%s", diff)
+	}
+
+	programs := map[string]Program{}
+	for _, program := range config.Programs {
+		programs[program.Name] = program
+	}
+	if _, ok := programs["outside"]; ok {
+		t.Errorf("Expected config above the git root to be ignored")
+	}
+	if _, ok := programs["repo"]; !ok {
+		t.Errorf("Expected program from the repository config")
+	}
+	shared := programs["shared"]
+	if shared.Image != "package-image" {
+		t.Errorf("Expected innermost config to win, got image %s", shared.Image)
+	}
+	if shared.Dockerfile != filepath.Join(packageDir, ".cubx", "Dockerfile") {
+		t.Errorf("Expected Dockerfile to be resolved next to its config, got %s", shared.Dockerfile)
+	}
+}
+
+func TestLoadConfigFileEnvFiles(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	cubxDir := filepath.Join(tempDir, ".cubx")
+	configPath := filepath.Join(cubxDir, "config.yaml")
+	writeConfigFiles(t, map[string]string{
+		configPath: `
+settings:
+  env_file: [.env.docker, /etc/cubx.env]
+programs:
+  - name: node
+    image: node
+    settings:
+      env_file: [../.env]
+    hooks:
+      - command: test
+        settings:
+          env_file: [test.env]
+profiles:
+  ci:
+    settings:
+      env_file: [ci.env]
+`,
+	})
+
+	config, err := loadConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	got := [][]string{
+		config.Settings.EnvFile,
+		config.Programs[0].Settings.EnvFile,
+		config.Programs[0].Hooks[0].Settings.EnvFile,
+		config.Profiles["ci"].Settings.EnvFile,
+	}
+	expected := [][]string{
+		{filepath.Join(cubxDir, ".env.docker"), "/etc/cubx.env"},
+		{filepath.Join(tempDir, ".env")},
+		{filepath.Join(cubxDir, "test.env")},
+		{filepath.Join(cubxDir, "ci.env")},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("Unexpected env files (-want +got):\n%s", diff)
+	}
+}
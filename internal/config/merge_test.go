@@ -2,8 +2,19 @@ package config
 
 import (
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
+// mergeAndResolve merges two layers the way loadLayers does.
+func mergeAndResolve(baseConfig, overrideConfig *ProgramConfig) (*ProgramConfig, error) {
+	merged, err := mergeConfigs(baseConfig, overrideConfig)
+	if err != nil {
+		return nil, err
+	}
+	return merged, resolveConfig(merged)
+}
+
 // Test function for merging configurations with override
 func TestMergeConfigs_Override(t *testing.T) {
 	baseConfig := &ProgramConfig{
@@ -66,7 +77,7 @@ func TestMergeConfigs_Override(t *testing.T) {
 		},
 	}
 
-	mergedConfig, err := mergeConfigs(baseConfig, overrideConfig)
+	mergedConfig, err := mergeAndResolve(baseConfig, overrideConfig)
 	if err != nil {
 		t.Fatalf("mergeConfigs failed: %v", err)
 	}
@@ -183,7 +194,7 @@ func TestMergeConfigs_AddNewProgram(t *testing.T) {
 		},
 	}
 
-	mergedConfig, err := mergeConfigs(baseConfig, overrideConfig)
+	mergedConfig, err := mergeAndResolve(baseConfig, overrideConfig)
 	if err != nil {
 		t.Fatalf("mergeConfigs failed: %v", err)
 	}
@@ -246,3 +257,91 @@ func TestMergeConfigs_AddNewProgram(t *testing.T) {
 		t.Errorf("expected Settings IgnorePaths to be [/override/path], got %v", mergedConfig.Settings.IgnorePaths)
 	}
 }
+
+func TestMergeConfigs_PatchProgram(t *testing.T) {
+	overrideConfig := &ProgramConfig{
+		Programs: []Program{
+			{Name: "node", Tag: "20"},
+		},
+	}
+
+	if err := validateConfigFile(overrideConfig, nil, "", nil); err != nil {
+		t.Fatalf("validation of the patch failed: %v", err)
+	}
+
+	mergedConfig, err := mergeAndResolve(getProgramConfig(), overrideConfig)
+	if err != nil {
+		t.Fatalf("mergeConfigs failed: %v", err)
+	}
+
+	if err := validateProgramConfig(mergedConfig); err != nil {
+		t.Fatalf("validation failed: %v", err)
+	}
+
+	for _, program := range mergedConfig.Programs {
+		if program.Name != "node" {
+			continue
+		}
+		expected := Program{
+			Name:        "node",
+			Image:       "node",
+			Command:     "node",
+			Description: "Execute Node.js programs",
+			Category:    "Node.js",
+			Tag:         "20",
+		}
+		if diff := cmp.Diff(expected, program); diff != "" {
+			t.Fatalf("unexpected patched program (-want +got):\n%s", diff)
+		}
+		return
+	}
+	t.Fatal("expected node program in the merged config")
+}
+
+func TestMergeConfigs_ReplaceProgram(t *testing.T) {
+	overrideConfig := &ProgramConfig{
+		Programs: []Program{
+			{Name: "node", Image: "node", Tag: "20-alpine", Replace: true},
+		},
+	}
+
+	mergedConfig, err := mergeAndResolve(getProgramConfig(), overrideConfig)
+	if err != nil {
+		t.Fatalf("mergeConfigs failed: %v", err)
+	}
+
+	for _, program := range mergedConfig.Programs {
+		if program.Name != "node" {
+			continue
+		}
+		expected := Program{Name: "node", Image: "node", Tag: "20-alpine"}
+		if diff := cmp.Diff(expected, program); diff != "" {
+			t.Fatalf("unexpected replaced program (-want +got):\n%s", diff)
+		}
+		return
+	}
+	t.Fatal("expected node program in the merged config")
+}
+
+func TestMergeConfigs_DisableProgram(t *testing.T) {
+	overrideConfig := &ProgramConfig{
+		Programs: []Program{
+			{Name: "ruby", Disabled: true},
+			{Name: "gem", Disabled: true},
+		},
+	}
+
+	mergedConfig, err := mergeAndResolve(getProgramConfig(), overrideConfig)
+	if err != nil {
+		t.Fatalf("mergeConfigs failed: %v", err)
+	}
+
+	if len(mergedConfig.Programs) != len(defaultPrograms)-2 {
+		t.Fatalf("expected %d programs, got %d", len(defaultPrograms)-2, len(mergedConfig.Programs))
+	}
+	for _, program := range mergedConfig.Programs {
+		if program.Name == "ruby" || program.Name == "gem" {
+			t.Errorf("expected program %s to be disabled", program.Name)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Mount types besides the default bind mount.
+const (
+	MountTypeBind   = "bind"
+	MountTypeTmpfs  = "tmpfs"
+	MountTypeVolume = "volume"
+)
+
+// Mount is an additional mount of the container. A string in the
+// src:dst[:opts] syntax of docker run -v is a shorthand for a bind mount, or
+// for a named volume when the source is a plain name.
+type Mount struct {
+	Type        string `yaml:"type,omitempty" validate:"oneof='' bind tmpfs volume"`
+	Source      string `yaml:"source,omitempty" validate:"required_unless=Type tmpfs,excluded_if=Type tmpfs"`
+	Target      string `yaml:"target" validate:"required,startswith=/"`
+	ReadOnly    bool   `yaml:"readonly,omitempty"`
+	Propagation string `yaml:"propagation,omitempty" validate:"excluded_if=Type tmpfs,excluded_if=Type volume,oneof='' private rprivate shared rshared slave rslave"`
+	// Relabel is the SELinux relabeling option of a bind mount: z for a shared
+	// and Z for a private label
+	Relabel string `yaml:"relabel,omitempty" validate:"excluded_if=Type tmpfs,excluded_if=Type volume,oneof='' z Z"`
+	// Size and Mode of a tmpfs mount, such as 64m and 1777
+	Size string `yaml:"size,omitempty" validate:"excluded_unless=Type tmpfs,omitempty,bytesize"`
+	Mode string `yaml:"mode,omitempty" validate:"excluded_unless=Type tmpfs,omitempty,filemode"`
+	// Labels are set on a named volume when it is created
+	Labels map[string]string `yaml:"labels,omitempty" validate:"excluded_unless=Type volume"`
+}
+
+// ParseMount parses a mount in the src:dst[:opts] syntax. A lone path is
+// mounted at the same path, opts is a comma-separated list of ro, rw, z, Z
+// and bind propagation modes.
+func ParseMount(spec string) (Mount, error) {
+	parts := splitMountSpec(spec)
+	// Keep the drive letter of Windows paths such as C:\data or C:/data with
+	// the path
+	if len(parts) > 1 && isDriveLetter(parts[0]) && (strings.HasPrefix(parts[1], `\`) || strings.HasPrefix(parts[1], "/")) {
+		parts = append([]string{parts[0] + ":" + parts[1]}, parts[2:]...)
+	}
+
+	m := Mount{Source: parts[0]}
+	switch len(parts) {
+	case 1:
+		m.Target = parts[0]
+	case 2, 3:
+		m.Target = parts[1]
+	default:
+		return Mount{}, fmt.Errorf("invalid mount %q, expected src:dst[:opts]", spec)
+	}
+	if m.Source == "" || m.Target == "" {
+		return Mount{}, fmt.Errorf("invalid mount %q, expected src:dst[:opts]", spec)
+	}
+	if len(parts) > 1 && isVolumeName(m.Source) {
+		m.Type = MountTypeVolume
+	}
+	if len(parts) < 3 {
+		return m, nil
+	}
+
+	for _, opt := range strings.Split(parts[2], ",") {
+		switch opt {
+		case "ro", "readonly":
+			m.ReadOnly = true
+		case "rw":
+			m.ReadOnly = false
+		case "z", "Z":
+			m.Relabel = opt
+		case "private", "rprivate", "shared", "rshared", "slave", "rslave":
+			m.Propagation = opt
+		default:
+			return Mount{}, fmt.Errorf("invalid mount %q: unknown option %q", spec, opt)
+		}
+	}
+	return m, nil
+}
+
+// splitMountSpec splits a mount on colons outside of ${...} variables, which
+// are expanded after parsing.
+func splitMountSpec(spec string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(spec); i++ {
+		switch {
+		case strings.HasPrefix(spec[i:], "${"):
+			depth++
+			i++
+		case spec[i] == '}' && depth > 0:
+			depth--
+		case spec[i] == ':' && depth == 0:
+			parts = append(parts, spec[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, spec[start:])
+}
+
+// isVolumeName reports whether the source of a mount names a volume rather
+// than a host path, as in docker run -v.
+func isVolumeName(source string) bool {
+	return !strings.ContainsAny(source, `/\`) && !strings.HasPrefix(source, ".") &&
+		!strings.HasPrefix(source, "~") && !strings.HasPrefix(source, "$")
+}
+
+// Kind returns the type of the mount, bind when it is not set.
+func (m Mount) Kind() string {
+	if m.Type == "" {
+		return MountTypeBind
+	}
+	return m.Type
+}
+
+func isDriveLetter(s string) bool {
+	return len(s) == 1 && (s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z')
+}
+
+// UnmarshalYAML accepts both the string and the mapping form of a mount.
+func (m *Mount) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var spec string
+		if err := node.Decode(&spec); err != nil {
+			return err
+		}
+		parsed, err := ParseMount(spec)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		*m = parsed
+		return nil
+	}
+
+	// Decode into a type without the method to avoid the recursion
+	type plain Mount
+	return node.Decode((*plain)(m))
+}
+
+// JSONSchema describes the two forms of a mount.
+func (m *Mount) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"type":        map[string]interface{}{"type": "string", "enum": []string{"", "bind", "tmpfs", "volume"}},
+					"source":      map[string]interface{}{"type": "string"},
+					"target":      map[string]interface{}{"type": "string"},
+					"readonly":    map[string]interface{}{"type": "boolean"},
+					"propagation": map[string]interface{}{"type": "string", "enum": []string{"", "private", "rprivate", "shared", "rshared", "slave", "rslave"}},
+					"relabel":     map[string]interface{}{"type": "string", "enum": []string{"", "z", "Z"}},
+					"size":        map[string]interface{}{"type": "string"},
+					"mode":        map[string]interface{}{"type": []string{"string", "integer"}},
+					"labels": map[string]interface{}{
+						"type":                 "object",
+						"additionalProperties": map[string]interface{}{"type": "string"},
+					},
+				},
+				"required":             []string{"target"},
+				"additionalProperties": false,
+			},
+		},
+	}
+}
+
+// String formats the mount in the src:dst[:opts] syntax.
+func (m Mount) String() string {
+	var opts []string
+	if m.ReadOnly {
+		opts = append(opts, "ro")
+	}
+	if m.Propagation != "" {
+		opts = append(opts, m.Propagation)
+	}
+	if m.Relabel != "" {
+		opts = append(opts, m.Relabel)
+	}
+	spec := m.Source + ":" + m.Target
+	if len(opts) > 0 {
+		spec += ":" + strings.Join(opts, ",")
+	}
+	return spec
+}
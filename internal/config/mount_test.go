@@ -0,0 +1,190 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseMount(t *testing.T) {
+	tests := map[string]Mount{
+		"/cache":                           {Source: "/cache", Target: "/cache"},
+		"/host/data:/data":                 {Source: "/host/data", Target: "/data"},
+		"/host/data:/data:ro":              {Source: "/host/data", Target: "/data", ReadOnly: true},
+		"/host/data:/data:rw,Z":            {Source: "/host/data", Target: "/data", Relabel: "Z"},
+		"/host/data:/data:ro,rshared,z":    {Source: "/host/data", Target: "/data", ReadOnly: true, Propagation: "rshared", Relabel: "z"},
+		`C:\Users\me\data:/data:ro`:        {Source: `C:\Users\me\data`, Target: "/data", ReadOnly: true},
+		"C:/Users/me/data:/data":           {Source: "C:/Users/me/data", Target: "/data"},
+		"${env:CACHE_DIR:-/tmp}:/cache":    {Source: "${env:CACHE_DIR:-/tmp}", Target: "/cache"},
+		"${PROJECT_ROOT}/.cache:/cache:ro": {Source: "${PROJECT_ROOT}/.cache", Target: "/cache", ReadOnly: true},
+		"npm-cache:/root/.npm":             {Type: "volume", Source: "npm-cache", Target: "/root/.npm"},
+		"./data:/data":                     {Source: "./data", Target: "/data"},
+	}
+	for spec, expected := range tests {
+		m, err := ParseMount(spec)
+		if err != nil {
+			t.Errorf("ParseMount(%s) failed: %v", spec, err)
+			continue
+		}
+		if diff := cmp.Diff(expected, m); diff != "" {
+			t.Errorf("ParseMount(%s) mismatch (-want +got):\n%s", spec, diff)
+		}
+	}
+
+	for _, spec := range []string{"", "/a:/b:ro:x", "/a:/b:noexec", ":/data"} {
+		if _, err := ParseMount(spec); err == nil {
+			t.Errorf("Expected ParseMount(%q) to fail", spec)
+		}
+	}
+}
+
+func TestMountString(t *testing.T) {
+	m := Mount{Source: "/host", Target: "/data", ReadOnly: true, Propagation: "rslave", Relabel: "z"}
+	if s := m.String(); s != "/host:/data:ro,rslave,z" {
+		t.Errorf("Unexpected mount string %q", s)
+	}
+}
+
+func TestLoadConfigFileMounts(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	writeConfigFiles(t, map[string]string{
+		configPath: `
+settings:
+  workdir_readonly: true
+  writable: [node_modules, dist]
+  mounts:
+    - /etc/ssl/certs:/etc/ssl/certs:ro
+    - source: /var/run/docker.sock
+      target: /var/run/docker.sock
+      readonly: true
+    - type: tmpfs
+      target: /tmp
+      size: 64m
+      mode: 1777
+`,
+	})
+
+	config, err := loadConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	expected := []Mount{
+		{Source: "/etc/ssl/certs", Target: "/etc/ssl/certs", ReadOnly: true},
+		{Source: "/var/run/docker.sock", Target: "/var/run/docker.sock", ReadOnly: true},
+		{Type: "tmpfs", Target: "/tmp", Size: "64m", Mode: "1777"},
+	}
+	if diff := cmp.Diff(expected, config.Settings.Mounts); diff != "" {
+		t.Errorf("Unexpected mounts (-want +got):\n%s", diff)
+	}
+	if !config.Settings.WorkdirReadonly || len(config.Settings.Writable) != 2 {
+		t.Errorf("Unexpected workdir settings: %+v", config.Settings)
+	}
+}
+
+func TestLoadConfigFileInvalidMounts(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		err     string
+	}{
+		"option": {err: `unknown option "noexec"`, content: `
+settings:
+  mounts: ["/a:/b:noexec"]
+`},
+		"missing target": {err: "mounts[0].target: is required", content: `
+settings:
+  mounts:
+    - source: /a
+`},
+		"propagation": {err: "'both' is not a valid value", content: `
+settings:
+  mounts:
+    - source: /a
+      target: /b
+      propagation: both
+`},
+		"relative target": {err: "mounts[0].target: 'cache' must start with /", content: `
+settings:
+  mounts: [cache]
+`},
+		"volume relabel": {err: "mounts[0].relabel: cannot be used when type is volume", content: `
+settings:
+  mounts: ["npm-cache:/root/.npm:Z"]
+`},
+		"tmpfs propagation": {err: "mounts[0].propagation: cannot be used when type is tmpfs", content: `
+settings:
+  mounts:
+    - type: tmpfs
+      target: /tmp
+      propagation: rshared
+`},
+		"tmpfs source": {err: "mounts[0].source: cannot be used when type is tmpfs", content: `
+settings:
+  mounts:
+    - type: tmpfs
+      source: /a
+      target: /tmp
+`},
+		"volume source": {err: "mounts[0].source: is required unless type is tmpfs", content: `
+settings:
+  mounts:
+    - type: volume
+      target: /data
+`},
+		"bind size": {err: "mounts[0].size: can only be used when type is tmpfs", content: `
+settings:
+  mounts:
+    - source: /a
+      target: /b
+      size: 1g
+`},
+		"tmpfs size": {err: "'lots' is not a valid size", content: `
+settings:
+  mounts:
+    - type: tmpfs
+      target: /tmp
+      size: lots
+`},
+		"tmpfs mode": {err: "'rwx' is not a valid octal file mode", content: `
+settings:
+  mounts:
+    - type: tmpfs
+      target: /tmp
+      mode: rwx
+`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assertLoadError(t, test.content, test.err)
+		})
+	}
+}
+
+func TestValidateFileUnknownMountKeys(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	writeConfigFiles(t, map[string]string{configPath: `settings:
+  mounts:
+    - /etc/ssl/certs:/etc/ssl/certs:ro
+    - source: /a
+      target: /b
+      readonyl: true
+`})
+
+	validationErrors, ok := ValidateFile(configPath).(ValidationErrors)
+	if !ok || len(validationErrors) != 1 {
+		t.Fatalf("Expected one validation error, got %v", validationErrors)
+	}
+	err := validationErrors[0]
+	got := fmt.Sprintf("%d:%d %s: %s", err.Line, err.Column, err.Path, err.Message)
+	if expected := "6:7 settings.mounts[1].readonyl: unknown key, did you mean 'readonly'?"; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,360 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError describes a problem found at a position of a config file.
+// File, Line and Column are empty for configs that were not read from a file.
+type ValidationError struct {
+	File    string
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	location := e.File
+	if location != "" && e.Line > 0 {
+		location = fmt.Sprintf("%s:%d:%d", location, e.Line, e.Column)
+	}
+	if location == "" {
+		return fmt.Sprintf("%s: %s", e.Path, e.Message)
+	}
+	return fmt.Sprintf("%s %s: %s", location, e.Path, e.Message)
+}
+
+// ValidationErrors is the list of problems found in a config.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	var lines []string
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// displayPath shortens the path of a config file relative to the working directory.
+func displayPath(filePath string) string {
+	if filePath == "" {
+		return ""
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return filePath
+	}
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return filePath
+	}
+	relPath, err := filepath.Rel(pwd, absPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return filePath
+	}
+	return relPath
+}
+
+// yamlKey returns the key a struct field is read from.
+func yamlKey(field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if key == "" {
+		return strings.ToLower(field.Name)
+	}
+	return key
+}
+
+// contentNode skips the document node of a parsed file.
+func contentNode(node *yaml.Node) *yaml.Node {
+	if node != nil && node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return node.Content[0]
+	}
+	return node
+}
+
+// mappingValue returns the value node of key in a mapping node.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// itemLabel names a sequence item by its name field when it has one,
+// e.g. programs[jq] instead of programs[3].
+func itemLabel(node *yaml.Node, index int) string {
+	if name := mappingValue(node, "name"); name != nil && name.Kind == yaml.ScalarNode && name.Value != "" {
+		return name.Value
+	}
+	return strconv.Itoa(index)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// checkUnknownKeys reports the keys of the node that do not match any field of t.
+func checkUnknownKeys(node *yaml.Node, t reflect.Type, path string, file string) ValidationErrors {
+	node = contentNode(node)
+	if node == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var errs ValidationErrors
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields := make(map[string]reflect.Type)
+		var keys []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			key := yamlKey(field)
+			fields[key] = field.Type
+			keys = append(keys, key)
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			fieldType, ok := fields[keyNode.Value]
+			if !ok {
+				message := "unknown key"
+				if suggestion := suggest(keyNode.Value, keys); suggestion != "" {
+					message = fmt.Sprintf("unknown key, did you mean '%s'?", suggestion)
+				}
+				errs = append(errs, &ValidationError{
+					File:    file,
+					Line:    keyNode.Line,
+					Column:  keyNode.Column,
+					Path:    joinPath(path, keyNode.Value),
+					Message: message,
+				})
+				continue
+			}
+			errs = append(errs, checkUnknownKeys(valueNode, fieldType, joinPath(path, keyNode.Value), file)...)
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for i, item := range node.Content {
+			errs = append(errs, checkUnknownKeys(item, t.Elem(), fmt.Sprintf("%s[%s]", path, itemLabel(item, i)), file)...)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			errs = append(errs, checkUnknownKeys(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value), file)...)
+		}
+	}
+	return errs
+}
+
+// suggest returns the candidate closest to value when it is close enough to be a typo.
+func suggest(value string, candidates []string) string {
+	best, bestDistance := "", -1
+	sorted := append([]string{}, candidates...)
+	sort.Strings(sorted)
+	for _, candidate := range sorted {
+		distance := levenshtein(value, candidate)
+		if bestDistance == -1 || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	limit := len(value) / 3
+	if limit < 2 {
+		limit = 2
+	}
+	if bestDistance == -1 || bestDistance > limit {
+		return ""
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// locate resolves a validator namespace such as ProgramConfig.Programs[0].Settings.Net
+// to the config path programs[jq].settings.net and the closest node of the file.
+func locate(root *yaml.Node, namespace string) (*yaml.Node, string) {
+	t := reflect.TypeOf(ProgramConfig{})
+	node := contentNode(root)
+	found := node != nil
+	path := ""
+
+	parts := strings.Split(namespace, ".")
+	for _, part := range parts[1:] {
+		name, index, hasIndex := strings.Cut(part, "[")
+		index = strings.TrimSuffix(index, "]")
+
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			break
+		}
+		key := yamlKey(field)
+		path = joinPath(path, key)
+		t = field.Type
+
+		if found {
+			if child := mappingValue(node, key); child != nil {
+				node = child
+			} else {
+				found = false
+			}
+		}
+
+		if !hasIndex {
+			continue
+		}
+
+		label := index
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array:
+			i, _ := strconv.Atoi(index)
+			if found && node.Kind == yaml.SequenceNode && i < len(node.Content) {
+				node = node.Content[i]
+				label = itemLabel(node, i)
+			} else {
+				found = false
+			}
+		case reflect.Map:
+			if child := mappingValue(node, index); found && child != nil {
+				node = child
+			} else {
+				found = false
+			}
+		}
+		path = fmt.Sprintf("%s[%s]", path, label)
+		t = t.Elem()
+	}
+
+	return node, path
+}
+
+// validationMessage describes a failed validation rule.
+func validationMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		values := strings.Fields(strings.ReplaceAll(err.Param(), "''", ""))
+		return fmt.Sprintf("'%v' is not a valid value, expected one of: %s", err.Value(), strings.Join(values, ", "))
+	case "required_without_all":
+		return fmt.Sprintf("is required unless one of %s is set", strings.ToLower(strings.ReplaceAll(err.Param(), " ", ", ")))
+	case "required_without":
+		return fmt.Sprintf("is required unless %s is set", strings.ToLower(err.Param()))
+	case "excluded_with":
+		return fmt.Sprintf("cannot be used together with %s", strings.ToLower(err.Param()))
+	case "required_unless":
+		return fmt.Sprintf("is required unless %s", conditionParam(err.Param()))
+	case "excluded_if":
+		return fmt.Sprintf("cannot be used when %s", conditionParam(err.Param()))
+	case "excluded_unless":
+		return fmt.Sprintf("can only be used when %s", conditionParam(err.Param()))
+	case "startswith":
+		return fmt.Sprintf("'%v' must start with %s", err.Value(), err.Param())
+	case "bytesize":
+		return fmt.Sprintf("'%v' is not a valid size, expected a number with an optional unit such as 64m", err.Value())
+	case "filemode":
+		return fmt.Sprintf("'%v' is not a valid octal file mode", err.Value())
+	case "glob":
+		return fmt.Sprintf("'%v' is not a valid glob pattern", err.Value())
+	case "regexp":
+		if _, compileErr := regexp.Compile(fmt.Sprint(err.Value())); compileErr != nil {
+			return fmt.Sprintf("invalid regular expression: %v", compileErr)
+		}
+	case "netports":
+		return fmt.Sprintf("ports cannot be published with net: %s", err.Param())
+	case "portconflict":
+		if ports, ok := err.Value().([]string); ok {
+			if portsErr := ValidatePorts(ports); portsErr != nil {
+				return portsErr.Error()
+			}
+		}
+	}
+	return fmt.Sprintf("'%v' is not a valid value", err.Value())
+}
+
+// conditionParam describes the "Field value" parameter of conditional rules.
+func conditionParam(param string) string {
+	field, value, _ := strings.Cut(param, " ")
+	return fmt.Sprintf("%s is %s", strings.ToLower(field), value)
+}
+
+// positionedErrors converts validator errors into errors pointing to the
+// config file. Without a file the paths are resolved against the encoded config.
+func positionedErrors(err error, config *ProgramConfig, root *yaml.Node, file string) error {
+	if err == nil {
+		return nil
+	}
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return fmt.Errorf("validation error: %w", err)
+	}
+
+	if root == nil {
+		root = &yaml.Node{}
+		if encodeErr := root.Encode(config); encodeErr != nil {
+			return fmt.Errorf("validation error: %w", err)
+		}
+	}
+
+	var errs ValidationErrors
+	for _, fieldErr := range validationErrors {
+		node, path := locate(root, fieldErr.Namespace())
+		positioned := &ValidationError{File: file, Path: path, Message: validationMessage(fieldErr)}
+		if node != nil && file != "" {
+			positioned.Line, positioned.Column = node.Line, node.Column
+		}
+		errs = append(errs, positioned)
+	}
+	return errs
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"dario.cat/mergo"
+)
+
+// ProfileEnv selects a profile when --profile is not given.
+const ProfileEnv = "CUBX_PROFILE"
+
+// ActiveProfile returns the profile selected by the flag, the CUBX_PROFILE
+// environment variable or default_profile, in this order of precedence.
+func (c *ProgramConfig) ActiveProfile(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	if env := os.Getenv(ProfileEnv); env != "" {
+		return env
+	}
+	return c.DefaultProfile
+}
+
+// ProfileSettings returns the settings of the profile for the program: the
+// settings of the profile overlaid with the ones scoped to the program.
+func (c *ProgramConfig) ProfileSettings(name, program string) (*Settings, error) {
+	profile, exists := c.Profiles[name]
+	if !exists {
+		var names []string
+		for profileName := range c.Profiles {
+			names = append(names, profileName)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("unknown profile %s, no profiles are configured", name)
+		}
+		return nil, fmt.Errorf("unknown profile %s, expected one of: %s", name, strings.Join(names, ", "))
+	}
+
+	settings, err := MergeSettings(profile.Settings, profile.Programs[program])
+	if err != nil {
+		return nil, fmt.Errorf("error merging profile %s: %w", name, err)
+	}
+	return &settings, nil
+}
+
+// mergeProfiles merges the profiles of two config layers by name. A profile of
+// the override layer patches the fields it sets.
+func mergeProfiles(base, override map[string]Profile) (map[string]Profile, error) {
+	if len(base) == 0 && len(override) == 0 {
+		return nil, nil
+	}
+
+	merged := make(map[string]Profile)
+	for name, profile := range base {
+		merged[name] = profile
+	}
+	for name, profile := range override {
+		current, exists := merged[name]
+		if !exists {
+			merged[name] = profile
+			continue
+		}
+
+		programs := make(map[string]Settings)
+		for program, settings := range current.Programs {
+			programs[program] = settings
+		}
+		for program, settings := range profile.Programs {
+			programSettings := programs[program]
+			if err := mergo.Merge(&programSettings, settings, mergo.WithOverride); err != nil {
+				return nil, err
+			}
+			programs[program] = programSettings
+		}
+
+		if err := mergo.Merge(&current.Settings, profile.Settings, mergo.WithOverride); err != nil {
+			return nil, err
+		}
+		current.Programs = programs
+		merged[name] = current
+	}
+	return merged, nil
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProfilesAcrossLayers(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	userConfigPath := filepath.Join(tempDir, "home", ".cubx", "config.yaml")
+	projectConfigPath := filepath.Join(tempDir, "project", ".cubx", "config.yaml")
+	writeConfigFiles(t, map[string]string{
+		userConfigPath: `
+profiles:
+  offline:
+    settings:
+      net: none
+      ignore_paths: [.env]
+  normal:
+    settings:
+      net: host
+`,
+		projectConfigPath: `
+default_profile: offline
+profiles:
+  offline:
+    settings:
+      ignore_paths: [.env, secrets]
+    programs:
+      npm:
+        env: [npm_config_offline=true]
+`,
+	})
+
+	userConfig, err := loadConfigFile(userConfigPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	projectConfig, err := loadConfigFile(projectConfigPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config, err := mergeAndResolve(userConfig, projectConfig)
+	if err != nil {
+		t.Fatalf("Failed to merge configs: %v", err)
+	}
+
+	if config.DefaultProfile != "offline" {
+		t.Errorf("Expected default profile offline, got %q", config.DefaultProfile)
+	}
+
+	settings, err := config.ProfileSettings("offline", "npm")
+	if err != nil {
+		t.Fatalf("Failed to resolve profile: %v", err)
+	}
+	expected := &Settings{
+		Net:         "none",
+		IgnorePaths: []string{".env", "secrets"},
+		Env:         []string{"npm_config_offline=true"},
+	}
+	if diff := cmp.Diff(expected, settings); diff != "" {
+		t.Errorf("Unexpected profile settings (-want +got):\n%s", diff)
+	}
+
+	settings, err = config.ProfileSettings("offline", "node")
+	if err != nil {
+		t.Fatalf("Failed to resolve profile: %v", err)
+	}
+	if len(settings.Env) != 0 {
+		t.Errorf("Expected program scoped settings to apply to npm only, got %v", settings.Env)
+	}
+
+	if _, err := config.ProfileSettings("paranoid", "npm"); err == nil || !strings.Contains(err.Error(), "expected one of: normal, offline") {
+		t.Errorf("Expected an unknown profile error, got %v", err)
+	}
+}
+
+func TestActiveProfile(t *testing.T) {
+	config := &ProgramConfig{DefaultProfile: "offline"}
+
+	t.Setenv(ProfileEnv, "")
+	if profile := config.ActiveProfile(""); profile != "offline" {
+		t.Errorf("Expected default profile, got %q", profile)
+	}
+
+	t.Setenv(ProfileEnv, "normal")
+	if profile := config.ActiveProfile(""); profile != "normal" {
+		t.Errorf("Expected profile from %s, got %q", ProfileEnv, profile)
+	}
+	if profile := config.ActiveProfile("paranoid"); profile != "paranoid" {
+		t.Errorf("Expected profile from the flag, got %q", profile)
+	}
+}
@@ -0,0 +1,24 @@
+package config
+
+import "path"
+
+// Matches reports whether the rule applies to the program.
+func (r *Rule) Matches(program *Program) bool {
+	if r.Category == "" && r.Image == "" && r.Name == "" {
+		return false
+	}
+	if r.Category != "" && r.Category != program.Category {
+		return false
+	}
+	if r.Image != "" {
+		if matched, _ := path.Match(r.Image, program.Image); !matched {
+			return false
+		}
+	}
+	if r.Name != "" {
+		if matched, _ := path.Match(r.Name, program.Name); !matched {
+			return false
+		}
+	}
+	return true
+}
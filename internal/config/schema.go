@@ -0,0 +1,142 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/eddort/cubx/internal/platform"
+)
+
+const schemaID = "https://raw.githubusercontent.com/eddort/cubx/main/docs/config.schema.json"
+
+// schemaProvider is implemented by config types that are not described well by
+// their Go type, such as values accepting several YAML forms.
+type schemaProvider interface {
+	JSONSchema() map[string]interface{}
+}
+
+// fileOptionalFields lists required fields a config file may omit because a
+// lower layer provides them, see programImagePattern.
+var fileOptionalFields = map[string]bool{
+	"Program.Image": true,
+}
+
+var schemaProviderType = reflect.TypeOf((*schemaProvider)(nil)).Elem()
+
+// JSONSchema generates the JSON Schema of a cubx config file from the config
+// types and their validation rules.
+func JSONSchema() ([]byte, error) {
+	definitions := make(map[string]interface{})
+	root := structSchema(reflect.TypeOf(ProgramConfig{}), definitions)
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["$id"] = schemaID
+	root["title"] = "cubx config"
+	root["definitions"] = definitions
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func structSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key := yamlKey(field)
+		rules := strings.Split(field.Tag.Get("validate"), ",")
+
+		schema := typeSchema(field.Type, definitions)
+		applyRules(schema, rules)
+		properties[key] = schema
+
+		if rules[0] == "required" && !fileOptionalFields[t.Name()+"."+field.Name] {
+			required = append(required, key)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func typeSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	if reflect.PtrTo(t).Implements(schemaProviderType) {
+		return reflect.New(t).Interface().(schemaProvider).JSONSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), definitions)
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), definitions)}
+	case reflect.Struct:
+		if _, exists := definitions[t.Name()]; !exists {
+			// Reserve the name first to support recursive types
+			definitions[t.Name()] = nil
+			definitions[t.Name()] = structSchema(t, definitions)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+	}
+	return map[string]interface{}{}
+}
+
+// applyRules translates the validate tag of a field into schema keywords.
+// Rules after dive apply to the items of a list.
+func applyRules(schema map[string]interface{}, rules []string) {
+	for i, rule := range rules {
+		if rule == "dive" {
+			if items, ok := schema["items"].(map[string]interface{}); ok {
+				applyRules(items, rules[i+1:])
+			}
+			return
+		}
+
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "oneof":
+			values := []string{}
+			for _, value := range strings.Fields(param) {
+				values = append(values, strings.Trim(value, "'"))
+			}
+			schema["enum"] = values
+		case "gte":
+			schema["minimum"] = json.Number(param)
+		case "platform":
+			platforms := append([]string{""}, platform.ValidPlatforms()...)
+			for j, value := range platforms {
+				platforms[j] = regexp.QuoteMeta(value)
+			}
+			schema["pattern"] = "^(" + strings.Join(platforms, "|") + ")$"
+		case "env":
+			schema["pattern"] = envPattern.String()
+		case "regexp":
+			schema["format"] = "regex"
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var updateSchema = flag.Bool("update-schema", false, "regenerate docs/config.schema.json")
+
+const schemaPath = "../../docs/config.schema.json"
+
+// TestJSONSchemaUpToDate fails when the config types change without the
+// published schema being regenerated with:
+//
+//	go test ./internal/config -run TestJSONSchemaUpToDate -update-schema
+func TestJSONSchemaUpToDate(t *testing.T) {
+	schema, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+
+	if *updateSchema {
+		if err := os.WriteFile(schemaPath, schema, 0644); err != nil {
+			t.Fatalf("Failed to write schema: %v", err)
+		}
+	}
+
+	published, err := os.ReadFile(schemaPath)
+	if err != nil {
+		t.Fatalf("Failed to read published schema: %v", err)
+	}
+
+	if diff := cmp.Diff(string(published), string(schema)); diff != "" {
+		t.Fatalf("%s is out of date, regenerate it with -update-schema (-published +generated):\n%s", schemaPath, diff)
+	}
+}
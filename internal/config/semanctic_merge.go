@@ -2,12 +2,16 @@ package config
 
 import (
 	"encoding/json"
+	"path"
+	"strings"
 
 	"dario.cat/mergo"
 )
 
 // mergeSettings merges two Settings objects with the values from the override having priority
-// and concatenates IgnorePaths slices without duplicates.
+// and concatenates IgnorePaths, EnvFile, EnvPassthrough, Ports, Expose and Writable slices without duplicates.
+// Env variables are merged by name, so the override can change a value set by the base,
+// and mounts are merged by target, so the override can replace a mount of the base.
 func MergeSettings(base, override Settings) (Settings, error) {
 	// Perform deep cloning of the base settings
 	merged := Settings{}
@@ -24,29 +28,91 @@ func MergeSettings(base, override Settings) (Settings, error) {
 		return base, err
 	}
 
-	// Merge IgnorePaths without duplicates
-	ignorePathSet := make(map[string]struct{})
-	allPaths := append(merged.IgnorePaths, base.IgnorePaths...)
-	allPaths = append(allPaths, override.IgnorePaths...)
+	merged.IgnorePaths = mergeUnique(merged.IgnorePaths, base.IgnorePaths, override.IgnorePaths)
+	// Env files are applied in order, the files of the override come last to win
+	merged.EnvFile = mergeUnique(base.EnvFile, override.EnvFile)
+	merged.EnvPassthrough = mergeUnique(merged.EnvPassthrough, base.EnvPassthrough, override.EnvPassthrough)
+	merged.Env = mergeEnv(base.Env, override.Env)
+	merged.Mounts = mergeMounts(base.Mounts, override.Mounts)
+	merged.Ports = mergeUnique(merged.Ports, base.Ports, override.Ports)
+	merged.Expose = mergeUnique(merged.Expose, base.Expose, override.Expose)
+	merged.Writable = mergeUnique(merged.Writable, base.Writable, override.Writable)
 
-	merged.IgnorePaths = nil // Clear the existing paths
+	return merged, nil
+}
+
+// mergeUnique concatenates slices without duplicates
+func mergeUnique(slices ...[]string) []string {
+	var result []string
+	set := make(map[string]struct{})
 
-	for _, path := range allPaths {
-		if _, exists := ignorePathSet[path]; !exists {
-			ignorePathSet[path] = struct{}{}
-			merged.IgnorePaths = append(merged.IgnorePaths, path)
+	for _, slice := range slices {
+		for _, value := range slice {
+			if _, exists := set[value]; !exists {
+				set[value] = struct{}{}
+				result = append(result, value)
+			}
 		}
 	}
 
-	return merged, nil
+	return result
+}
+
+// mergeEnv merges KEY=VAL lists by key, values from the override win
+// but keep the position of the variable in the base.
+func mergeEnv(base, override []string) []string {
+	var result []string
+	index := make(map[string]int)
+
+	for _, env := range append(append([]string{}, base...), override...) {
+		key, _, _ := strings.Cut(env, "=")
+		if i, exists := index[key]; exists {
+			result[i] = env
+			continue
+		}
+		index[key] = len(result)
+		result = append(result, env)
+	}
+
+	return result
+}
+
+// mergeMounts merges mounts by target, mounts from the override win
+// but keep the position of the mount in the base.
+func mergeMounts(base, override []Mount) []Mount {
+	var result []Mount
+	index := make(map[string]int)
+
+	for _, m := range append(append([]Mount{}, base...), override...) {
+		target := path.Clean(m.Target)
+		if i, exists := index[target]; exists {
+			result[i] = m
+			continue
+		}
+		index[target] = len(result)
+		result = append(result, m)
+	}
+
+	return result
 }
 
 // semanticMerge updates the given config by applying inherited settings
 func semanticMerge(config *ProgramConfig) error {
 	// Merge global settings into each program's settings
 	for i, program := range config.Programs {
-		// Step 1: Merge global settings into program settings
-		mergedSettings, err := MergeSettings(config.Settings, program.Settings)
+		// Step 1: Merge global settings and the matching rules into program settings
+		inherited := config.Settings
+		for _, rule := range config.Rules {
+			if !rule.Matches(&program) {
+				continue
+			}
+			ruleSettings, err := MergeSettings(inherited, rule.Settings)
+			if err != nil {
+				return err
+			}
+			inherited = ruleSettings
+		}
+		mergedSettings, err := MergeSettings(inherited, program.Settings)
 		if err != nil {
 			return err
 		}
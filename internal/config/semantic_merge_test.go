@@ -78,3 +78,87 @@ func TestSemanticMerge(t *testing.T) {
 		t.Errorf("expected global settings to be %+v, but got %+v", expectedGlobalSettings, configGlobalSettings)
 	}
 }
+
+func TestMergeSettingsEnv(t *testing.T) {
+	base := Settings{
+		Env:            []string{"NODE_ENV=development", "DEBUG=1"},
+		EnvFile:        []string{".env", ".env.program"},
+		EnvPassthrough: []string{"AWS_*"},
+	}
+	override := Settings{
+		Env:            []string{"NODE_ENV=production", "TOKEN"},
+		EnvFile:        []string{".env.local", ".env"},
+		EnvPassthrough: []string{"GITHUB_TOKEN"},
+	}
+
+	merged, err := MergeSettings(base, override)
+	if err != nil {
+		t.Fatalf("MergeSettings failed: %v", err)
+	}
+
+	expected := Settings{
+		Env:            []string{"NODE_ENV=production", "DEBUG=1", "TOKEN"},
+		EnvFile:        []string{".env", ".env.program", ".env.local"},
+		EnvPassthrough: []string{"GITHUB_TOKEN", "AWS_*"},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected merged settings to be %+v, but got %+v", expected, merged)
+	}
+}
+
+func TestSemanticMergeRules(t *testing.T) {
+	config := &ProgramConfig{
+		Settings: Settings{Net: "none", IgnorePaths: []string{".env"}},
+		Rules: []Rule{
+			{Category: "Ethereum", Settings: Settings{Net: "host"}},
+			{Image: "ghcr.io/our-org/*", Settings: Settings{Mounts: []Mount{{Source: "/cache", Target: "/cache"}}}},
+			{Name: "cast*", Settings: Settings{Net: "bridge"}},
+		},
+		Programs: []Program{
+			{Name: "forge", Image: "ghcr.io/our-org/foundry", Category: "Ethereum"},
+			{Name: "cast", Image: "ghcr.io/foundry-rs/foundry", Category: "Ethereum"},
+			{Name: "anvil", Image: "ghcr.io/our-org/foundry", Category: "Ethereum", Settings: Settings{Net: "none"}},
+			{Name: "jq", Image: "ghcr.io/jqlang/jq"},
+		},
+	}
+
+	if err := semanticMerge(config); err != nil {
+		t.Fatalf("semanticMerge failed: %v", err)
+	}
+
+	expected := map[string]Settings{
+		"forge": {Net: "host", IgnorePaths: []string{".env"}, Mounts: []Mount{{Source: "/cache", Target: "/cache"}}},
+		"cast":  {Net: "bridge", IgnorePaths: []string{".env"}},
+		"anvil": {Net: "none", IgnorePaths: []string{".env"}, Mounts: []Mount{{Source: "/cache", Target: "/cache"}}},
+		"jq":    {Net: "none", IgnorePaths: []string{".env"}},
+	}
+	for _, program := range config.Programs {
+		if !reflect.DeepEqual(program.Settings, expected[program.Name]) {
+			t.Errorf("Unexpected settings of %s: got %+v, expected %+v", program.Name, program.Settings, expected[program.Name])
+		}
+	}
+}
+
+func TestMergeSettingsMounts(t *testing.T) {
+	base := Settings{Mounts: []Mount{
+		{Source: "/host/cache", Target: "/cache"},
+		{Type: MountTypeTmpfs, Target: "/tmp"},
+	}}
+	override := Settings{Mounts: []Mount{
+		{Type: MountTypeVolume, Source: "cache", Target: "/cache/"},
+		{Source: "/etc/ssl", Target: "/etc/ssl", ReadOnly: true},
+	}}
+
+	merged, err := MergeSettings(base, override)
+	if err != nil {
+		t.Fatalf("MergeSettings failed: %v", err)
+	}
+	expected := []Mount{
+		{Type: MountTypeVolume, Source: "cache", Target: "/cache/"},
+		{Type: MountTypeTmpfs, Target: "/tmp"},
+		{Source: "/etc/ssl", Target: "/etc/ssl", ReadOnly: true},
+	}
+	if !reflect.DeepEqual(merged.Mounts, expected) {
+		t.Errorf("expected mounts %+v, got %+v", expected, merged.Mounts)
+	}
+}
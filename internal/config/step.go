@@ -0,0 +1,49 @@
+package config
+
+import "gopkg.in/yaml.v3"
+
+// Step is a host-side action run before or after a program. It either runs a
+// command in the host shell or invokes another cubx program. A plain string is
+// a shorthand for a host command.
+type Step struct {
+	Run  string `yaml:"run,omitempty" validate:"required_without=Cubx,excluded_with=Cubx"`
+	Cubx string `yaml:"cubx,omitempty"`
+}
+
+// UnmarshalYAML accepts both the string and the mapping form of a step.
+func (s *Step) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&s.Run)
+	}
+
+	// Decode into a type without the method to avoid the recursion
+	type plain Step
+	return node.Decode((*plain)(s))
+}
+
+// JSONSchema describes the two forms of a step.
+func (s *Step) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"run":  map[string]interface{}{"type": "string"},
+					"cubx": map[string]interface{}{"type": "string"},
+				},
+				"additionalProperties": false,
+				"minProperties":        1,
+				"maxProperties":        1,
+			},
+		},
+	}
+}
+
+// String describes the step in messages.
+func (s Step) String() string {
+	if s.Cubx != "" {
+		return "cubx " + s.Cubx
+	}
+	return s.Run
+}
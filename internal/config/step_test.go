@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadConfigFileSteps(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	writeConfigFiles(t, map[string]string{
+		configPath: `
+programs:
+  - name: npm
+    image: node
+    before:
+      - ./scripts/npmrc.sh
+      - cubx: node scripts/check.js
+    after:
+      - run: rm -f .npmrc
+    hooks:
+      - command: publish
+        before: [echo publishing]
+`,
+	})
+
+	config, err := loadConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	program := config.Programs[0]
+	if diff := cmp.Diff([]Step{{Run: "./scripts/npmrc.sh"}, {Cubx: "node scripts/check.js"}}, program.Before); diff != "" {
+		t.Errorf("Unexpected before steps (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]Step{{Run: "rm -f .npmrc"}}, program.After); diff != "" {
+		t.Errorf("Unexpected after steps (-want +got):\n%s", diff)
+	}
+
+	applied, err := program.WithHooks(program.Hooks)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(applied.Before) != 3 || applied.Before[2].Run != "echo publishing" {
+		t.Errorf("Expected hook steps after the program steps, got %v", applied.Before)
+	}
+}
+
+func TestLoadConfigFileInvalidSteps(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		err     string
+	}{
+		"both": {err: "cannot be used together with cubx", content: `
+programs:
+  - name: npm
+    image: node
+    before:
+      - run: echo
+        cubx: node
+`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assertLoadError(t, test.content, test.err)
+		})
+	}
+}
+
+func TestValidateFileUnknownStepKeys(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	writeConfigFiles(t, map[string]string{configPath: `programs:
+  - name: npm
+    image: node
+    after:
+      - rn: echo
+`})
+
+	var got []string
+	validationErrors, _ := ValidateFile(configPath).(ValidationErrors)
+	for _, err := range validationErrors {
+		got = append(got, fmt.Sprintf("%d:%d %s: %s", err.Line, err.Column, err.Path, err.Message))
+	}
+	if !contains(got, "5:9 programs[npm].after[0].rn: unknown key, did you mean 'run'?") {
+		t.Errorf("Expected a positioned unknown key warning, got %v", got)
+	}
+}
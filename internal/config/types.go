@@ -2,41 +2,113 @@ package config
 
 import "reflect"
 
+// TTY modes of a program. In auto mode a TTY is allocated only when cubx
+// itself is attached to a terminal.
+const (
+	TTYAuto   = "auto"
+	TTYAlways = "always"
+	TTYNever  = "never"
+)
+
 type CLI struct {
 	IsSelectMode bool     `yaml:"is_select_mode"`
 	FileIgnores  []string `yaml:"file_ignores"`
 	ShowConfig   string   `yaml:"show_config"`
+	ShowRaw      bool     `yaml:"show_raw"`
+	DryRun       bool     `yaml:"dry_run"`
+	ShowSecrets  bool     `yaml:"show_secrets"`
+	Verbose      bool     `yaml:"verbose"`
+	Profile      string   `yaml:"profile"`
+	Output       string   `yaml:"output"`
 	Session      bool     `yaml:"session"`
+	Env          []string `yaml:"env"`
+	EnvFiles     []string `yaml:"env_files"`
+	Ports        []string `yaml:"ports"`
+	Expose       []string `yaml:"expose"`
 }
 
+// Hook changes a program for the invocations it matches. A hook matches when
+// every criterion it sets matches the arguments, see Hook.Matches.
 type Hook struct {
-	Command  string   `yaml:"command"`
-	Settings Settings `yaml:"settings"`
+	Name       string     `yaml:"name,omitempty"`
+	When       *Condition `yaml:"when,omitempty"`
+	Command    string     `yaml:"command" validate:"required_without_all=Regex Flags"`
+	Regex      string     `yaml:"regex,omitempty" validate:"omitempty,regexp"`
+	Flags      []string   `yaml:"flags,omitempty"`
+	ValueFlags []string   `yaml:"value_flags,omitempty"`
+	Image      string     `yaml:"image,omitempty"`
+	Tag        string     `yaml:"tag,omitempty"`
+	Run        string     `yaml:"run,omitempty"`
+	Serializer string     `yaml:"serializer,omitempty" validate:"oneof='' default string testhandler"`
+	Before     []Step     `yaml:"before,omitempty" validate:"dive"`
+	After      []Step     `yaml:"after,omitempty" validate:"dive"`
+	Settings   Settings   `yaml:"settings"`
 }
 
 type Program struct {
-	Name        string   `yaml:"name" validate:"required"`
-	Image       string   `yaml:"image" validate:"required"`
-	Command     string   `yaml:"command"`
-	Serializer  string   `yaml:"serializer" validate:"oneof='' default string testhandler"`
-	Description string   `yaml:"description"`
-	Tag         string   `yaml:"tag"`
-	Category    string   `yaml:"category"`
-	Hooks       []Hook   `yaml:"hooks" validate:"dive"`
-	Settings    Settings `yaml:"settings"`
-	Dockerfile  string   `yaml:"dockerfile"`
+	Name        string     `yaml:"name" validate:"required"`
+	When        *Condition `yaml:"when,omitempty"`
+	Image       string     `yaml:"image" validate:"required"`
+	Command     string     `yaml:"command"`
+	Serializer  string     `yaml:"serializer" validate:"oneof='' default string testhandler"`
+	Description string     `yaml:"description"`
+	Tag         string     `yaml:"tag"`
+	Category    string     `yaml:"category"`
+	Hooks       []Hook     `yaml:"hooks" validate:"dive"`
+	HookMatch   string     `yaml:"hook_match,omitempty" validate:"oneof='' first all"`
+	Before      []Step     `yaml:"before,omitempty" validate:"dive"`
+	After       []Step     `yaml:"after,omitempty" validate:"dive"`
+	Settings    Settings   `yaml:"settings"`
+	Cache       []string   `yaml:"cache,omitempty" validate:"dive,startswith=/"`
+	CacheScope  string     `yaml:"cache_scope,omitempty" validate:"oneof='' project global"`
+	Dockerfile  string     `yaml:"dockerfile"`
+	TTY         string     `yaml:"tty" validate:"oneof='' auto always never"`
+	Extends     string     `yaml:"extends,omitempty"`
+	Replace     bool       `yaml:"replace,omitempty"`
+	Disabled    bool       `yaml:"disabled,omitempty"`
 }
 
 type Settings struct {
-	Net         string   `yaml:"net" validate:"oneof='' none host bridge"`
-	IgnorePaths []string `yaml:"ignore_paths"`
-	Mounts      []string `yaml:"mounts"`
-	Platform    string   `yaml:"platform" validate:"platform"`
+	When            *Condition `yaml:"when,omitempty"`
+	Net             string     `yaml:"net" validate:"oneof='' none host bridge"`
+	IgnorePaths     []string   `yaml:"ignore_paths"`
+	Mounts          []Mount    `yaml:"mounts" validate:"dive"`
+	Platform        string     `yaml:"platform" validate:"platform"`
+	Init            bool       `yaml:"init"`
+	StopTimeout     int        `yaml:"stop_timeout" validate:"gte=0"`
+	Env             []string   `yaml:"env" validate:"dive,env"`
+	EnvFile         []string   `yaml:"env_file"`
+	EnvPassthrough  []string   `yaml:"env_passthrough"`
+	Ports           []string   `yaml:"ports" validate:"dive,port"`
+	Expose          []string   `yaml:"expose"`
+	WorkdirReadonly bool       `yaml:"workdir_readonly"`
+	Writable        []string   `yaml:"writable"`
 }
 
 type ProgramConfig struct {
-	Programs []Program `yaml:"programs" validate:"required,dive"`
-	Settings Settings  `yaml:"settings"`
+	Include        []string           `yaml:"include"`
+	Programs       []Program          `yaml:"programs" validate:"dive"`
+	Settings       Settings           `yaml:"settings"`
+	Rules          []Rule             `yaml:"rules,omitempty" validate:"dive"`
+	Profiles       map[string]Profile `yaml:"profiles,omitempty" validate:"dive"`
+	DefaultProfile string             `yaml:"default_profile,omitempty"`
+}
+
+// Rule applies settings to the programs it matches, so that they do not have
+// to be repeated in every program. Image and Name are glob patterns and every
+// criterion that is set must match.
+type Rule struct {
+	Category string   `yaml:"category,omitempty" validate:"required_without_all=Image Name"`
+	Image    string   `yaml:"image,omitempty" validate:"omitempty,glob"`
+	Name     string   `yaml:"name,omitempty" validate:"omitempty,glob"`
+	Settings Settings `yaml:"settings"`
+}
+
+// Profile is a named overlay of settings picked with --profile, CUBX_PROFILE
+// or default_profile. Programs holds additional settings for single programs.
+type Profile struct {
+	Settings Settings            `yaml:"settings"`
+	Programs map[string]Settings `yaml:"programs,omitempty" validate:"dive"`
 }
 
 func (s *Settings) IsEmpty() bool {
@@ -3,9 +3,15 @@ package config
 import (
 	"fmt"
 	"github.com/eddort/cubx/internal/platform"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
 	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
 )
 
 func validatePlatform(fl validator.FieldLevel) bool {
@@ -21,40 +27,145 @@ func validatePlatform(fl validator.FieldLevel) bool {
 	return platform.IsValidOsArch(parts[0], parts[1])
 }
 
-// setDefaults sets default values for fields that are not set.
-func setDefaults(config *ProgramConfig) {
-	for i := range config.Programs {
-		if config.Programs[i].Serializer == "" {
-			config.Programs[i].Serializer = "default"
+var envPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(=.*)?$`)
+
+func validateEnv(fl validator.FieldLevel) bool {
+	return envPattern.MatchString(fl.Field().String())
+}
+
+func validateRegexp(fl validator.FieldLevel) bool {
+	_, err := regexp.Compile(fl.Field().String())
+	return err == nil
+}
+
+func validateGlob(fl validator.FieldLevel) bool {
+	_, err := path.Match(fl.Field().String(), "")
+	return err == nil
+}
+
+func validateByteSize(fl validator.FieldLevel) bool {
+	_, err := units.RAMInBytes(fl.Field().String())
+	return err == nil
+}
+
+func validateFileMode(fl validator.FieldLevel) bool {
+	_, err := strconv.ParseUint(fl.Field().String(), 8, 32)
+	return err == nil
+}
+
+func validatePort(fl validator.FieldLevel) bool {
+	_, err := nat.ParsePortSpec(fl.Field().String())
+	return err == nil
+}
+
+// ValidatePorts checks that the port specs in the host:container/proto syntax
+// are well-formed and that no host port is bound to different container ports.
+func ValidatePorts(ports []string) error {
+	bindings := make(map[string]string)
+	for _, port := range ports {
+		mappings, err := nat.ParsePortSpec(port)
+		if err != nil {
+			return fmt.Errorf("invalid port %q: %w", port, err)
 		}
-		if config.Programs[i].Tag == "" {
-			config.Programs[i].Tag = "latest"
+		for _, mapping := range mappings {
+			if mapping.Binding.HostPort == "" {
+				continue
+			}
+			hostPort := mapping.Binding.HostIP + ":" + mapping.Binding.HostPort + "/" + mapping.Port.Proto()
+			if containerPort, exists := bindings[hostPort]; exists && containerPort != string(mapping.Port) {
+				return fmt.Errorf("host port %s is published to both %s and %s", hostPort, containerPort, mapping.Port)
+			}
+			bindings[hostPort] = string(mapping.Port)
 		}
 	}
+	return nil
+}
+
+func validateSettings(sl validator.StructLevel) {
+	settings := sl.Current().Interface().(Settings)
+	if len(settings.Ports) == 0 {
+		return
+	}
+	// Published ports need the bridge network, host and none cannot publish
+	if settings.Net == "host" || settings.Net == "none" {
+		sl.ReportError(settings.Ports, "Ports", "ports", "netports", settings.Net)
+	}
+	if err := ValidatePorts(settings.Ports); err != nil {
+		sl.ReportError(settings.Ports, "Ports", "ports", "portconflict", "")
+	}
 }
 
 func getValidator() *validator.Validate {
 	validate := validator.New()
 	validate.RegisterValidation("platform", validatePlatform)
+	validate.RegisterValidation("env", validateEnv)
+	validate.RegisterValidation("port", validatePort)
+	validate.RegisterValidation("regexp", validateRegexp)
+	validate.RegisterValidation("glob", validateGlob)
+	validate.RegisterValidation("bytesize", validateByteSize)
+	validate.RegisterValidation("filemode", validateFileMode)
+	validate.RegisterStructValidation(validateSettings, Settings{})
 	return validate
 }
 
-func validateProgramConfig(config *ProgramConfig) error {
-	// Set default values
-	setDefaults(config)
+// ValidateSettings checks settings that are put together at run time, such as
+// the settings of a program merged with the command line flags.
+func ValidateSettings(settings *Settings) error {
+	err := getValidator().Struct(settings)
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+	var messages []string
+	for _, fieldErr := range validationErrors {
+		messages = append(messages, fmt.Sprintf("%s: %s", strings.TrimPrefix(fieldErr.Namespace(), "Settings."), validationMessage(fieldErr)))
+	}
+	return fmt.Errorf("invalid settings: %s", strings.Join(messages, "; "))
+}
+
+// programImagePattern matches the image of a program. A config file may omit it
+// when the program only patches a program declared by a lower layer.
+var programImagePattern = regexp.MustCompile(`^ProgramConfig\.Programs\[\d+\]\.Image$`)
 
+// validateConfigFile validates a single config layer before it is merged.
+// Errors point to the positions of the parsed file.
+// Blocks skipped by their when condition, given as validator namespaces, are
+// not validated.
+func validateConfigFile(config *ProgramConfig, root *yaml.Node, file string, skipped []string) error {
 	validate := getValidator()
 
-	err := validate.Struct(config)
-	if err != nil {
-		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			var errorMessages []string
-			for _, err := range validationErrors {
-				errorMessages = append(errorMessages, fmt.Sprintf("Field validation error on '%s': '%v' is not a valid value", err.Field(), err.Value()))
+	err := validate.StructFiltered(config, func(ns []byte) bool {
+		return programImagePattern.Match(ns)
+	})
+	if validationErrors, ok := err.(validator.ValidationErrors); ok && len(skipped) > 0 {
+		var kept validator.ValidationErrors
+		for _, fieldErr := range validationErrors {
+			if !inNamespaces(fieldErr.Namespace(), skipped) {
+				kept = append(kept, fieldErr)
 			}
-			return fmt.Errorf("validation errors: %s", errorMessages)
 		}
-		return fmt.Errorf("validation error: %w", err)
+		if len(kept) == 0 {
+			return nil
+		}
+		err = kept
 	}
-	return nil
+	return positionedErrors(err, config, root, file)
+}
+
+// inNamespaces reports whether the validator namespace ns lies inside one of
+// the namespaces.
+func inNamespaces(ns string, namespaces []string) bool {
+	for _, prefix := range namespaces {
+		if ns == prefix || strings.HasPrefix(ns, prefix+".") || strings.HasPrefix(ns, prefix+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateProgramConfig validates the merged configuration.
+func validateProgramConfig(config *ProgramConfig) error {
+	validate := getValidator()
+
+	return positionedErrors(validate.Struct(config), config, nil, "")
 }
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidatePorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		ports   []string
+		wantErr bool
+	}{
+		{name: "container port only", ports: []string{"8080"}},
+		{name: "host and container", ports: []string{"3000:3000", "8545:8545/tcp", "53:53/udp"}},
+		{name: "host ip", ports: []string{"127.0.0.1:8080:80"}},
+		{name: "same binding twice", ports: []string{"3000:3000", "3000:3000"}},
+		{name: "same host port different proto", ports: []string{"53:53/tcp", "53:53/udp"}},
+		{name: "malformed", ports: []string{"abc:80"}, wantErr: true},
+		{name: "invalid proto", ports: []string{"80:80/http"}, wantErr: true},
+		{name: "conflict", ports: []string{"3000:3000", "3000:4000"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePorts(tt.ports)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePorts(%v) error = %v, wantErr %v", tt.ports, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSettingsPortsWithHostNetwork(t *testing.T) {
+	config := &ProgramConfig{
+		Programs: []Program{
+			{
+				Name:     "anvil",
+				Image:    "ghcr.io/foundry-rs/foundry",
+				Settings: Settings{Net: "host", Ports: []string{"8545:8545"}},
+			},
+		},
+	}
+
+	if err := validateProgramConfig(config); err == nil {
+		t.Fatal("Expected error for ports with host network, got nil")
+	}
+}
+
+func TestValidateSettingsPortsWithoutBridge(t *testing.T) {
+	tests := []struct {
+		settings Settings
+		err      string
+	}{
+		{settings: Settings{Net: "host", Ports: []string{"8545:8545"}}, err: "ports cannot be published with net: host"},
+		{settings: Settings{Net: "none", Ports: []string{"8545:8545"}}, err: "ports cannot be published with net: none"},
+		{settings: Settings{Net: "bridge", Ports: []string{"8545:8545"}}},
+		{settings: Settings{Ports: []string{"8545:8545"}}},
+	}
+
+	for _, tt := range tests {
+		err := ValidateSettings(&tt.settings)
+		if tt.err == "" && err != nil {
+			t.Errorf("ValidateSettings(%+v) unexpected error: %v", tt.settings, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("ValidateSettings(%+v) error = %v, expected %q", tt.settings, err, tt.err)
+		}
+	}
+}
+
+func TestValidateFilePositions(t *testing.T) {
+	tempDir, cleanup := setupTempDir(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	configContent := []byte(`programs:
+  - name: jq
+    image: ghcr.io/jqlang/jq
+    settings:
+      net: xyz
+      ignore_path: [.env]
+  - name: curl
+    imag: curlimages/curl
+program: []
+`)
+	if err := os.WriteFile(configPath, configContent, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	err := ValidateFile(configPath)
+	validationErrors, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	var got []string
+	for _, validationErr := range validationErrors {
+		got = append(got, fmt.Sprintf("%d:%d %s: %s", validationErr.Line, validationErr.Column, validationErr.Path, validationErr.Message))
+	}
+	expected := []string{
+		"5:12 programs[jq].settings.net: 'xyz' is not a valid value, expected one of: none, host, bridge",
+		"6:7 programs[jq].settings.ignore_path: unknown key, did you mean 'ignore_paths'?",
+		"8:5 programs[curl].imag: unknown key, did you mean 'image'?",
+		"9:1 program: unknown key, did you mean 'programs'?",
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatalf("Unexpected validation errors (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,69 @@
+package docker
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/eddort/cubx/internal/config"
+	"github.com/eddort/cubx/internal/streams"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// resolveTTY reports whether the container should be created with a TTY.
+// In auto mode a TTY is only allocated when both stdin and stdout are
+// terminals, so that cubx can be used in pipes and redirections.
+func resolveTTY(mode string, in *streams.In, out *streams.Out) bool {
+	switch mode {
+	case config.TTYAlways:
+		return true
+	case config.TTYNever:
+		return false
+	}
+	return in.IsTerminal() && out.IsTerminal()
+}
+
+// setRawTerminal puts the host terminal into raw mode so that key presses are
+// passed to the container as is. The returned function restores the terminal.
+func setRawTerminal(in *streams.In, out *streams.Out) (func(), error) {
+	restore := func() {
+		in.RestoreTerminal()
+		out.RestoreTerminal()
+	}
+	if err := in.SetRawTerminal(); err != nil {
+		return nil, fmt.Errorf("error setting raw mode on the input terminal: %w", err)
+	}
+	if err := out.SetRawTerminal(); err != nil {
+		restore()
+		return nil, fmt.Errorf("error setting raw mode on the output terminal: %w", err)
+	}
+	return restore, nil
+}
+
+// streamIO connects the host standard streams to the attached container.
+// Without a TTY the output is multiplexed by Docker and is split back into
+// stdout and stderr, and the container's stdin is half-closed at EOF.
+// The returned channel is closed once the container output has been copied.
+func streamIO(resp types.HijackedResponse, tty bool, in *streams.In, out *streams.Out) <-chan struct{} {
+	outputDone := make(chan struct{})
+
+	go func() {
+		defer close(outputDone)
+		if tty {
+			io.Copy(out, resp.Reader)
+		} else {
+			stdcopy.StdCopy(out, os.Stderr, resp.Reader)
+		}
+	}()
+
+	go func() {
+		io.Copy(resp.Conn, in)
+		if !tty {
+			resp.CloseWrite()
+		}
+	}()
+
+	return outputDone
+}
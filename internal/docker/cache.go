@@ -0,0 +1,134 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/eddort/cubx/internal/config"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+// CacheVolume is a named volume that backs a cache directory of a program.
+type CacheVolume struct {
+	Name    string
+	Program string
+	Path    string
+	Scope   string
+	// Project is the project root of a project cache
+	Project string
+	// Size is the disk usage in bytes, -1 when it is unknown
+	Size int64
+	// RefCount is the number of containers using the volume, -1 when it is unknown
+	RefCount int64
+}
+
+// cacheFilters selects the cache volumes of program, or of all programs when
+// program is empty.
+func cacheFilters(program string) filters.Args {
+	if program == "" {
+		return filters.NewArgs(filters.Arg("label", config.CacheProgramLabel))
+	}
+	return filters.NewArgs(filters.Arg("label", config.CacheProgramLabel+"="+program))
+}
+
+func newCacheVolume(v *volume.Volume) CacheVolume {
+	cache := CacheVolume{
+		Name:     v.Name,
+		Program:  v.Labels[config.CacheProgramLabel],
+		Path:     v.Labels[config.CachePathLabel],
+		Scope:    v.Labels[config.CacheScopeLabel],
+		Project:  v.Labels[config.CacheProjectLabel],
+		Size:     -1,
+		RefCount: -1,
+	}
+	if v.UsageData != nil {
+		cache.Size = v.UsageData.Size
+		cache.RefCount = v.UsageData.RefCount
+	}
+	return cache
+}
+
+func sortCaches(caches []CacheVolume) {
+	sort.Slice(caches, func(i, j int) bool {
+		if caches[i].Program != caches[j].Program {
+			return caches[i].Program < caches[j].Program
+		}
+		if caches[i].Path != caches[j].Path {
+			return caches[i].Path < caches[j].Path
+		}
+		return caches[i].Project < caches[j].Project
+	})
+}
+
+// ListCaches returns the cache volumes of program, or of all programs when
+// program is empty. The disk usage is only computed when withSize is set, as
+// it can take a while for large volumes.
+func ListCaches(program string, withSize bool) ([]CacheVolume, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("error creating Docker client: %w", err)
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	var volumes []*volume.Volume
+	if withSize {
+		usage, err := cli.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.VolumeObject}})
+		if err != nil {
+			return nil, fmt.Errorf("error computing the disk usage: %w", err)
+		}
+		for _, v := range usage.Volumes {
+			if _, ok := v.Labels[config.CacheProgramLabel]; ok && (program == "" || v.Labels[config.CacheProgramLabel] == program) {
+				volumes = append(volumes, v)
+			}
+		}
+	} else {
+		list, err := cli.VolumeList(ctx, volume.ListOptions{Filters: cacheFilters(program)})
+		if err != nil {
+			return nil, fmt.Errorf("error listing volumes: %w", err)
+		}
+		volumes = list.Volumes
+	}
+
+	caches := make([]CacheVolume, 0, len(volumes))
+	for _, v := range volumes {
+		caches = append(caches, newCacheVolume(v))
+	}
+	sortCaches(caches)
+	return caches, nil
+}
+
+// PruneCaches removes the cache volumes of program, or of all programs when
+// program is empty, and returns the removed ones. Volumes used by a container
+// are kept and reported in the error.
+func PruneCaches(program string) ([]CacheVolume, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("error creating Docker client: %w", err)
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	list, err := cli.VolumeList(ctx, volume.ListOptions{Filters: cacheFilters(program)})
+	if err != nil {
+		return nil, fmt.Errorf("error listing volumes: %w", err)
+	}
+
+	var removed []CacheVolume
+	var errs []error
+	for _, v := range list.Volumes {
+		if err := cli.VolumeRemove(ctx, v.Name, false); err != nil {
+			errs = append(errs, fmt.Errorf("error removing volume %s: %w", v.Name, err))
+			continue
+		}
+		removed = append(removed, newCacheVolume(v))
+	}
+	sortCaches(removed)
+	return removed, errors.Join(errs...)
+}
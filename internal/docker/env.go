@@ -0,0 +1,139 @@
+package docker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/eddort/cubx/internal/config"
+)
+
+// getENV builds the container environment. Variables are applied in order of
+// increasing priority: the built-in ones, host variables matched by
+// env_passthrough, env files and finally explicit env entries. The names of
+// the variables whose values come from the host environment or an env file
+// are returned as well.
+func getENV(currentCWD string, settings *config.Settings) ([]string, map[string]bool, error) {
+	containerENVS := []string{}
+	hostEnv := make(map[string]bool)
+	add := func(envs []string, fromHost bool) {
+		for _, env := range envs {
+			key, _, _ := strings.Cut(env, "=")
+			hostEnv[key] = fromHost
+		}
+		containerENVS = append(containerENVS, envs...)
+	}
+
+	termEnv := os.Getenv("TERM")
+	if termEnv != "" {
+		add([]string{fmt.Sprintf("TERM=%s", termEnv)}, false)
+	}
+
+	add([]string{fmt.Sprintf("CUBX_HOST_CWD=%s", currentCWD)}, false)
+
+	add(passthroughEnv(settings.EnvPassthrough), true)
+
+	for _, envFile := range settings.EnvFile {
+		fileEnvs, err := readEnvFile(envFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		add(fileEnvs, true)
+	}
+
+	for _, env := range settings.Env {
+		// A bare name takes the value of the host variable
+		add(expandEnv([]string{env}), !strings.Contains(env, "="))
+	}
+
+	for key, fromHost := range hostEnv {
+		if !fromHost {
+			delete(hostEnv, key)
+		}
+	}
+	return dedupeEnv(containerENVS), hostEnv, nil
+}
+
+// passthroughEnv returns the host variables whose names match any of the
+// glob patterns, e.g. AWS_*.
+func passthroughEnv(patterns []string) []string {
+	var envs []string
+	if len(patterns) == 0 {
+		return envs
+	}
+	for _, env := range os.Environ() {
+		key, _, _ := strings.Cut(env, "=")
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, key); matched {
+				envs = append(envs, env)
+				break
+			}
+		}
+	}
+	return envs
+}
+
+// readEnvFile parses a file with KEY=VAL lines. Empty lines and lines
+// starting with # are skipped.
+func readEnvFile(envFile string) ([]string, error) {
+	absPath, err := filepath.Abs(envFile)
+	if err != nil {
+		return nil, fmt.Errorf("error converting path to absolute: %w", err)
+	}
+
+	file, err := os.Open(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening env file: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, strings.TrimPrefix(line, "export "))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading env file %s: %w", absPath, err)
+	}
+
+	return expandEnv(lines), nil
+}
+
+// expandEnv resolves bare KEY entries to the value of the host variable,
+// following the docker --env semantics. Unset host variables are skipped.
+func expandEnv(envs []string) []string {
+	var result []string
+	for _, env := range envs {
+		if strings.Contains(env, "=") {
+			result = append(result, env)
+			continue
+		}
+		if value, ok := os.LookupEnv(env); ok {
+			result = append(result, env+"="+value)
+		}
+	}
+	return result
+}
+
+// dedupeEnv keeps the last value of every variable.
+func dedupeEnv(envs []string) []string {
+	var result []string
+	index := make(map[string]int)
+	for _, env := range envs {
+		key, _, _ := strings.Cut(env, "=")
+		if i, exists := index[key]; exists {
+			result[i] = env
+			continue
+		}
+		index[key] = len(result)
+		result = append(result, env)
+	}
+	return result
+}
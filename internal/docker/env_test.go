@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eddort/cubx/internal/config"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetENVHostEnv(t *testing.T) {
+	t.Setenv("CUBX_TEST_AWS_KEY", "secret")
+	t.Setenv("CUBX_TEST_TOKEN", "token")
+
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("DB_PASSWORD=hunter2\nMODE=file\n"), 0644); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	_, hostEnv, err := getENV("/work", &config.Settings{
+		EnvPassthrough: []string{"CUBX_TEST_AWS_*"},
+		EnvFile:        []string{envFile},
+		Env:            []string{"CUBX_TEST_TOKEN", "MODE=explicit", "NODE_ENV=test"},
+	})
+	if err != nil {
+		t.Fatalf("getENV failed: %v", err)
+	}
+
+	expected := map[string]bool{"CUBX_TEST_AWS_KEY": true, "DB_PASSWORD": true, "CUBX_TEST_TOKEN": true}
+	if diff := cmp.Diff(expected, hostEnv); diff != "" {
+		t.Errorf("Unexpected host env (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetENVEnvFileOverride(t *testing.T) {
+	dir := t.TempDir()
+	programFile := filepath.Join(dir, "program.env")
+	flagFile := filepath.Join(dir, "flag.env")
+	if err := os.WriteFile(programFile, []byte("MODE=program\nPROGRAM_ONLY=1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	if err := os.WriteFile(flagFile, []byte("MODE=flag\n"), 0644); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	settings, err := config.MergeSettings(config.Settings{EnvFile: []string{programFile}}, config.Settings{EnvFile: []string{flagFile}})
+	if err != nil {
+		t.Fatalf("MergeSettings failed: %v", err)
+	}
+	envs, _, err := getENV("/work", &settings)
+	if err != nil {
+		t.Fatalf("getENV failed: %v", err)
+	}
+
+	values := map[string]string{}
+	for _, env := range envs {
+		key, value, _ := strings.Cut(env, "=")
+		values[key] = value
+	}
+	if values["MODE"] != "flag" || values["PROGRAM_ONLY"] != "1" {
+		t.Errorf("Expected the override env file to win, got %v", envs)
+	}
+}
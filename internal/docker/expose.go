@@ -0,0 +1,159 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/eddort/cubx/internal/ignore"
+)
+
+// ExposeGitTracked is the expose entry that stands for the files tracked by
+// git in the working tree.
+const ExposeGitTracked = "git-tracked"
+
+// exposure decides which paths of the working directory are visible in the
+// container when the expose setting is used.
+type exposure struct {
+	root    string
+	matcher *ignore.Matcher
+	tracked map[string]bool
+	// trackedDirs holds the directories that contain a tracked file
+	trackedDirs map[string]bool
+}
+
+// findExposedPaths returns the paths of root that match the expose patterns.
+// A directory whose whole content is exposed is returned instead of its
+// entries, so that it is mounted once. A single path with an empty Rel means
+// the whole root is exposed. It is an error when nothing matches, since the
+// container would run without /app.
+func findExposedPaths(root string, expose []string) ([]ignore.Path, error) {
+	e := &exposure{root: root, matcher: &ignore.Matcher{}}
+	var patterns []string
+	for _, entry := range expose {
+		if entry == ExposeGitTracked {
+			tracked, err := gitTrackedFiles(root)
+			if err != nil {
+				return nil, err
+			}
+			e.tracked = tracked
+			e.trackedDirs = parentDirs(tracked)
+			continue
+		}
+		patterns = append(patterns, entry)
+	}
+	if err := e.matcher.Add(root, patterns...); err != nil {
+		return nil, err
+	}
+
+	full, paths, err := e.visit("")
+	if err != nil {
+		return nil, err
+	}
+	if full {
+		return []ignore.Path{{Rel: "", IsDir: true}}, nil
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("expose %s matches no path in %s", strings.Join(expose, ", "), root)
+	}
+	return paths, nil
+}
+
+// visit reports whether everything under the directory rel is exposed, and
+// otherwise returns its exposed entries.
+func (e *exposure) visit(rel string) (bool, []ignore.Path, error) {
+	dir := filepath.Join(e.root, filepath.FromSlash(rel))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsPermission(err) && rel != "" {
+			return false, nil, nil
+		}
+		return false, nil, fmt.Errorf("error reading %s: %w", dir, err)
+	}
+	if len(entries) == 0 {
+		return false, nil, nil
+	}
+
+	full := true
+	var paths []ignore.Path
+	for _, entry := range entries {
+		entryRel := path.Join(rel, entry.Name())
+		entryPath := filepath.Join(e.root, filepath.FromSlash(entryRel))
+		entryMatched := e.matcher.Match(entryPath, entry.IsDir())
+
+		switch {
+		case entry.IsDir() && entryMatched:
+			paths = append(paths, ignore.Path{Rel: entryRel, IsDir: true})
+		case entry.IsDir() && entry.Name() == ".git":
+			// The repository metadata is only exposed when asked for explicitly
+			full = false
+		case entry.IsDir() && !e.trackedDirs[entryRel] && !e.matcher.MayMatchBelow(entryPath):
+			// Nothing inside can be exposed, such as untracked node_modules
+			full = false
+		case entry.IsDir():
+			subFull, subPaths, err := e.visit(entryRel)
+			if err != nil {
+				return false, nil, err
+			}
+			if subFull {
+				paths = append(paths, ignore.Path{Rel: entryRel, IsDir: true})
+			} else {
+				full = false
+				paths = append(paths, subPaths...)
+			}
+		case entryMatched || e.tracked[entryRel]:
+			paths = append(paths, ignore.Path{Rel: entryRel})
+		default:
+			full = false
+		}
+	}
+	if full {
+		return true, nil, nil
+	}
+	return false, paths, nil
+}
+
+// gitTrackedFiles lists the files tracked by git under dir, relative to dir.
+func gitTrackedFiles(dir string) (map[string]bool, error) {
+	cmd := exec.Command("git", "ls-files", "-z", "--cached")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("expose %s requires a git repository: %w: %s", ExposeGitTracked, err, strings.TrimSpace(stderr.String()))
+	}
+
+	tracked := make(map[string]bool)
+	for _, file := range strings.Split(string(out), "\x00") {
+		if file != "" {
+			tracked[file] = true
+		}
+	}
+	return tracked, nil
+}
+
+// parentDirs returns every directory that contains one of the files.
+func parentDirs(files map[string]bool) map[string]bool {
+	dirs := make(map[string]bool)
+	for file := range files {
+		for dir := path.Dir(file); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	return dirs
+}
+
+// isExposed reports whether the path rel lies inside one of the exposed paths.
+func isExposed(rel string, exposed []ignore.Path) bool {
+	for _, p := range exposed {
+		if p.Rel == "" || rel == p.Rel || strings.HasPrefix(rel, p.Rel+"/") {
+			return true
+		}
+	}
+	return false
+}
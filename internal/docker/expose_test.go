@@ -0,0 +1,141 @@
+package docker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eddort/cubx/internal/ignore"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeTree(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
+func TestFindExposedPaths(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root,
+		"package.json",
+		"src/index.js",
+		"src/lib/util.js",
+		"test/index.test.js",
+		"test/fixtures/data.json",
+		".env",
+	)
+
+	tests := []struct {
+		expose   []string
+		expected []ignore.Path
+	}{
+		{
+			expose: []string{"src/", "package.json"},
+			expected: []ignore.Path{
+				{Rel: "package.json"},
+				{Rel: "src", IsDir: true},
+			},
+		},
+		{
+			expose: []string{"*.js"},
+			expected: []ignore.Path{
+				{Rel: "src", IsDir: true},
+				{Rel: "test/index.test.js"},
+			},
+		},
+		{
+			expose:   []string{"*"},
+			expected: []ignore.Path{{Rel: "", IsDir: true}},
+		},
+	}
+
+	for _, test := range tests {
+		paths, err := findExposedPaths(root, test.expose)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(test.expected, paths); diff != "" {
+			t.Errorf("findExposedPaths(%v) mismatch (-want +got):\n%s", test.expose, diff)
+		}
+	}
+
+	if _, err := findExposedPaths(root, []string{"missing"}); err == nil || !strings.Contains(err.Error(), "matches no path") {
+		t.Errorf("Expected an error for expose patterns that match nothing, got %v", err)
+	}
+}
+
+func TestFindExposedPathsGitTracked(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	writeTree(t, root,
+		"main.go",
+		"pkg/a.go",
+		"pkg/b.go",
+		"docs/readme.md",
+		"docs/draft.md",
+		"node_modules/dep/index.js",
+		".env",
+	)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "main.go", "pkg", "docs/readme.md"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+
+	paths, err := findExposedPaths(root, []string{ExposeGitTracked, ".env"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []ignore.Path{
+		{Rel: ".env"},
+		{Rel: "docs/readme.md"},
+		{Rel: "main.go"},
+		{Rel: "pkg", IsDir: true},
+	}
+	if diff := cmp.Diff(expected, paths); diff != "" {
+		t.Errorf("findExposedPaths mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParentDirs(t *testing.T) {
+	dirs := parentDirs(map[string]bool{"main.go": true, "pkg/a.go": true, "pkg/sub/b.go": true})
+	expected := map[string]bool{"pkg": true, "pkg/sub": true}
+	if diff := cmp.Diff(expected, dirs); diff != "" {
+		t.Errorf("parentDirs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestIsExposed(t *testing.T) {
+	exposed := []ignore.Path{{Rel: "src", IsDir: true}, {Rel: "package.json"}}
+	tests := map[string]bool{
+		"src":            true,
+		"src/.env":       true,
+		"srcs/.env":      false,
+		"package.json":   true,
+		"node_modules/x": false,
+	}
+	for rel, expected := range tests {
+		if got := isExposed(rel, exposed); got != expected {
+			t.Errorf("isExposed(%s) = %v, expected %v", rel, got, expected)
+		}
+	}
+}
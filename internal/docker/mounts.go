@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/eddort/cubx/internal/config"
+	"github.com/eddort/cubx/internal/ignore"
+
 	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-units"
 )
 
 func getCurrentDir() (string, error) {
@@ -26,73 +31,226 @@ func createTempDir() (string, error) {
 	return tempDir, nil
 }
 
-func parseMountsString(input string) mount.Mount {
-	parts := strings.Split(input, ":")
-	if len(parts) == 1 {
-		return mount.Mount{
-			Type:   mount.TypeBind,
-			Source: parts[0],
-			Target: parts[0],
-		}
+// hostSetup prepares the host side of the mounts. PlanRun uses one that does
+// not change the host.
+type hostSetup struct {
+	// emptyDir provides the empty directories that hide ignored directories
+	emptyDir func() (string, error)
+	// mkdirAll creates the writable directories that do not exist yet
+	mkdirAll func(path string) error
+}
+
+var runHost = hostSetup{
+	emptyDir: createTempDir,
+	mkdirAll: func(path string) error { return os.MkdirAll(path, 0755) },
+}
+
+// VolumeLabel marks the named volumes created by cubx.
+const VolumeLabel = "cubx.owned"
+
+// userMount converts a mount of the settings. Relative bind sources are
+// resolved against the working directory. Named volumes that do not exist yet
+// are created by Docker with the cubx label.
+func userMount(cwd string, m config.Mount) (mount.Mount, error) {
+	result := mount.Mount{
+		Type:     mount.Type(m.Kind()),
+		Source:   m.Source,
+		Target:   m.Target,
+		ReadOnly: m.ReadOnly,
 	}
-	return mount.Mount{
-		Type:   mount.TypeBind,
-		Source: parts[0],
-		Target: parts[1],
+
+	switch m.Kind() {
+	case config.MountTypeBind:
+		if !filepath.IsAbs(result.Source) {
+			result.Source = filepath.Join(cwd, result.Source)
+		}
+		if m.Propagation != "" {
+			result.BindOptions = &mount.BindOptions{Propagation: mount.Propagation(m.Propagation)}
+		}
+	case config.MountTypeVolume:
+		labels := map[string]string{VolumeLabel: "true"}
+		for key, value := range m.Labels {
+			labels[key] = value
+		}
+		result.VolumeOptions = &mount.VolumeOptions{Labels: labels}
+	case config.MountTypeTmpfs:
+		options := &mount.TmpfsOptions{}
+		if m.Size != "" {
+			size, err := units.RAMInBytes(m.Size)
+			if err != nil {
+				return mount.Mount{}, fmt.Errorf("invalid tmpfs size %q: %w", m.Size, err)
+			}
+			options.SizeBytes = size
+		}
+		if m.Mode != "" {
+			mode, err := strconv.ParseUint(m.Mode, 8, 32)
+			if err != nil {
+				return mount.Mount{}, fmt.Errorf("invalid tmpfs mode %q: %w", m.Mode, err)
+			}
+			options.Mode = os.FileMode(mode)
+		}
+		result.TmpfsOptions = options
 	}
+	return result, nil
 }
 
-func generateMounts(cwd string, ignores []string, user_mounts []string) ([]mount.Mount, error) {
-	mounts := []mount.Mount{
-		{
-			Type:   mount.TypeBind,
-			Source: cwd,
-			Target: "/app",
-		},
+// userBind formats a bind mount for HostConfig.Binds, which unlike the mount
+// API supports SELinux relabeling.
+func userBind(cwd string, m config.Mount) string {
+	source := m.Source
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(cwd, source)
 	}
+	return config.Mount{
+		Source:      source,
+		Target:      m.Target,
+		ReadOnly:    m.ReadOnly,
+		Propagation: m.Propagation,
+		Relabel:     m.Relabel,
+	}.String()
+}
 
-	for _, s := range user_mounts {
-		mount := parseMountsString(s)
-		mounts = append(mounts, mount)
+// generateMounts mounts the working directory to /app along with the user
+// mounts, and hides the ignored paths: files behind /dev/null and directories
+// behind an empty directory provided by host. Ignored paths are matched
+// with gitignore patterns from the .cubxignore file of the project root and
+// from ignore_paths, which are anchored to the working directory. When expose
+// is set, only the exposed paths of the working directory are mounted. User
+// bind mounts that need relabeling are returned as binds.
+func generateMounts(cwd string, settings *config.Settings, host hostSetup) ([]mount.Mount, []string, error) {
+	dir, err := getCurrentDir()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	for _, ignore := range ignores {
-		// Convert the path to an absolute path
-		absPath, err := filepath.Abs(ignore)
+	exposed := []ignore.Path{{Rel: "", IsDir: true}}
+	if len(settings.Expose) > 0 {
+		exposed, err = findExposedPaths(dir, settings.Expose)
 		if err != nil {
-			return nil, fmt.Errorf("error converting path to absolute: %w", err)
+			return nil, nil, err
 		}
+	}
+
+	var mounts []mount.Mount
+	for _, path := range exposed {
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   filepath.Join(cwd, filepath.FromSlash(path.Rel)),
+			Target:   strings.TrimSuffix("/app/"+path.Rel, "/"),
+			ReadOnly: settings.WorkdirReadonly,
+		})
+	}
 
-		// Check if the path exists
-		fileInfo, err := os.Stat(absPath)
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("path does not exist: %s", absPath)
+	if settings.WorkdirReadonly {
+		writable, err := findWritablePaths(dir, settings.Writable, host.mkdirAll)
+		if err != nil {
+			return nil, nil, err
 		}
+		for _, rel := range writable {
+			if !isExposed(rel, exposed) {
+				continue
+			}
+			mounts = append(mounts, mount.Mount{
+				Type:   mount.TypeBind,
+				Source: filepath.Join(cwd, filepath.FromSlash(rel)),
+				Target: "/app/" + rel,
+			})
+		}
+	}
 
+	var binds []string
+	for _, m := range settings.Mounts {
+		if m.Relabel != "" && m.Kind() == config.MountTypeBind {
+			binds = append(binds, userBind(cwd, m))
+			continue
+		}
+		converted, err := userMount(cwd, m)
 		if err != nil {
-			return nil, fmt.Errorf("error stating path: %w", err)
+			return nil, nil, err
 		}
+		mounts = append(mounts, converted)
+	}
+
+	ignored, err := findIgnoredPaths(settings.IgnorePaths)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		var source string
-		if fileInfo.IsDir() {
-			// Create a temporary empty directory
-			source, err = createTempDir()
+	for _, path := range ignored {
+		// Paths that are not exposed are not in the container anyway
+		if !isExposed(path.Rel, exposed) {
+			continue
+		}
+
+		source := "/dev/null"
+		if path.IsDir {
+			source, err = host.emptyDir()
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
-		} else {
-			// Use /dev/null for files
-			source = "/dev/null"
 		}
 
-		mount := mount.Mount{
+		mounts = append(mounts, mount.Mount{
 			Type:   mount.TypeBind,
 			Source: source,
-			Target: "/app/" + filepath.Base(absPath),
+			Target: "/app/" + path.Rel,
+		})
+	}
+
+	return mounts, binds, nil
+}
+
+// findWritablePaths returns the writable paths relative to dir and
+// slash-separated. Missing paths are created as directories with mkdirAll, so
+// that e.g. node_modules can be written on a fresh checkout.
+func findWritablePaths(dir string, writable []string, mkdirAll func(path string) error) ([]string, error) {
+	var paths []string
+	for _, w := range writable {
+		p := w
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
 		}
+		p = filepath.Clean(p)
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("writable path %s is outside of the working directory", w)
+		}
+		if rel == "." {
+			return nil, fmt.Errorf("writable path %s is the working directory itself, unset workdir_readonly instead", w)
+		}
+		if _, err := os.Stat(p); err != nil {
+			if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("error checking writable path %s: %w", w, err)
+			}
+			if err := mkdirAll(p); err != nil {
+				return nil, fmt.Errorf("error creating writable path %s: %w", w, err)
+			}
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	return paths, nil
+}
 
-		mounts = append(mounts, mount)
+// findIgnoredPaths returns the paths of the working directory matched by the
+// .cubxignore file and the ignore patterns, which are anchored to the working
+// directory.
+func findIgnoredPaths(ignores []string) ([]ignore.Path, error) {
+	dir, err := getCurrentDir()
+	if err != nil {
+		return nil, err
+	}
+	projectRoot, err := config.ProjectRoot()
+	if err != nil {
+		return nil, err
 	}
 
-	return mounts, nil
+	matcher := &ignore.Matcher{}
+	if err := matcher.AddFile(filepath.Join(projectRoot, ignore.FileName)); err != nil {
+		return nil, err
+	}
+	// Ignore paths are anchored to the working directory like paths
+	if err := matcher.AddAnchored(dir, ignores...); err != nil {
+		return nil, err
+	}
+	return matcher.Walk(dir)
 }
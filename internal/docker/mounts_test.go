@@ -0,0 +1,139 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eddort/cubx/internal/config"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUserMount(t *testing.T) {
+	tests := []struct {
+		mount    config.Mount
+		expected mount.Mount
+	}{
+		{
+			mount:    config.Mount{Source: "data", Target: "/data", ReadOnly: true},
+			expected: mount.Mount{Type: mount.TypeBind, Source: "/work/data", Target: "/data", ReadOnly: true},
+		},
+		{
+			mount: config.Mount{Source: "/host", Target: "/host", Propagation: "rslave"},
+			expected: mount.Mount{
+				Type: mount.TypeBind, Source: "/host", Target: "/host",
+				BindOptions: &mount.BindOptions{Propagation: mount.PropagationRSlave},
+			},
+		},
+		{
+			mount: config.Mount{Type: config.MountTypeVolume, Source: "npm-cache", Target: "/root/.npm"},
+			expected: mount.Mount{
+				Type: mount.TypeVolume, Source: "npm-cache", Target: "/root/.npm",
+				VolumeOptions: &mount.VolumeOptions{Labels: map[string]string{VolumeLabel: "true"}},
+			},
+		},
+		{
+			mount: config.Mount{Type: config.MountTypeTmpfs, Target: "/tmp", Size: "64m", Mode: "1777"},
+			expected: mount.Mount{
+				Type: mount.TypeTmpfs, Target: "/tmp",
+				TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 64 << 20, Mode: 01777},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		m, err := userMount("/work", test.mount)
+		if err != nil {
+			t.Fatalf("userMount(%+v) failed: %v", test.mount, err)
+		}
+		if diff := cmp.Diff(test.expected, m); diff != "" {
+			t.Errorf("userMount(%+v) mismatch (-want +got):\n%s", test.mount, diff)
+		}
+	}
+
+	if bind := userBind("/work", config.Mount{Source: "data", Target: "/data", Relabel: "Z"}); bind != "/work/data:/data:Z" {
+		t.Errorf("Unexpected bind %q", bind)
+	}
+}
+
+func TestGenerateMountsRelabel(t *testing.T) {
+	settings := &config.Settings{Mounts: []config.Mount{
+		{Source: "data", Target: "/data", Relabel: "Z"},
+		{Type: config.MountTypeVolume, Source: "cache", Target: "/cache", Labels: map[string]string{"cubx.cache": "npm"}},
+	}}
+	host := hostSetup{
+		emptyDir: func() (string, error) { return "/empty", nil },
+		mkdirAll: func(string) error { return nil },
+	}
+
+	mounts, binds, err := generateMounts("/work", settings, host)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"/work/data:/data:Z"}, binds); diff != "" {
+		t.Errorf("Unexpected binds (-want +got):\n%s", diff)
+	}
+	var volume *mount.Mount
+	for i := range mounts {
+		if mounts[i].Type == mount.TypeVolume {
+			volume = &mounts[i]
+		}
+	}
+	if volume == nil || volume.VolumeOptions == nil || volume.VolumeOptions.Labels[VolumeLabel] != "true" || volume.VolumeOptions.Labels["cubx.cache"] != "npm" {
+		t.Errorf("Expected the volume to keep its labels, got %+v", volume)
+	}
+}
+
+func TestDockerRunArgsMounts(t *testing.T) {
+	spec := &RunSpec{
+		Config: &container.Config{Image: "node", WorkingDir: "/app"},
+		HostConfig: &container.HostConfig{
+			NetworkMode: "host",
+			Mounts: []mount.Mount{
+				{Type: mount.TypeTmpfs, Target: "/tmp", TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 1024, Mode: 01777}},
+				{Type: mount.TypeVolume, Source: "cache", Target: "/cache", VolumeOptions: &mount.VolumeOptions{Labels: map[string]string{VolumeLabel: "true"}}},
+			},
+			Binds: []string{"/work/data:/data:Z"},
+		},
+	}
+
+	args := strings.Join(spec.DockerRunArgs(), " ")
+	for _, expected := range []string{
+		"--mount type=tmpfs,target=/tmp,tmpfs-size=1024,tmpfs-mode=1777",
+		"--mount type=volume,source=cache,target=/cache,volume-label=cubx.owned=true",
+		"-v /work/data:/data:Z",
+	} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("Expected %q in %s", expected, args)
+		}
+	}
+}
+
+func TestFindWritablePaths(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "node_modules/.keep", "dist/app.js")
+
+	paths, err := findWritablePaths(root, []string{"node_modules", "./dist/", "coverage", filepath.Join(root, "dist/app.js")}, func(path string) error {
+		return os.MkdirAll(path, 0755)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"node_modules", "dist", "coverage", "dist/app.js"}, paths); diff != "" {
+		t.Errorf("findWritablePaths mismatch (-want +got):\n%s", diff)
+	}
+	if info, err := os.Stat(filepath.Join(root, "coverage")); err != nil || !info.IsDir() {
+		t.Errorf("Expected the missing writable directory to be created, got %v", err)
+	}
+
+	noop := func(string) error { return nil }
+	for _, writable := range []string{"../outside", ".", "./"} {
+		if _, err := findWritablePaths(root, []string{writable}, noop); err == nil {
+			t.Errorf("Expected writable path %q to be rejected", writable)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package docker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eddort/cubx/internal/config"
+	"github.com/eddort/cubx/internal/streams"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// emptyDirPlaceholder stands for the temporary directories that mask ignored
+// directories when the container is only planned.
+const emptyDirPlaceholder = "<empty temporary directory>"
+
+// RunSpec is the container cubx creates for an invocation.
+type RunSpec struct {
+	Config     *container.Config
+	HostConfig *container.HostConfig
+	Platform   string
+	// HostEnv holds the names of the variables whose values come from the
+	// host environment or an env file
+	HostEnv map[string]bool
+}
+
+// PlanRun resolves the container that RunImageAndCommand would create without
+// talking to the Docker daemon or creating anything on the host. The temporary
+// directories masking ignored directories are shown as a placeholder, and
+// missing writable directories are not created.
+func PlanRun(dockerImage string, command []string, ttyMode string, settings *config.Settings) (*RunSpec, error) {
+	currentCWD, err := getCWD()
+	if err != nil {
+		return nil, err
+	}
+	tty := resolveTTY(ttyMode, streams.NewIn(), streams.NewOut())
+	return newRunSpec(currentCWD, dockerImage, command, tty, settings, hostSetup{
+		emptyDir: func() (string, error) { return emptyDirPlaceholder, nil },
+		mkdirAll: func(string) error { return nil },
+	})
+}
+
+// newRunSpec builds the container configuration. host prepares the host side
+// of the mounts.
+func newRunSpec(currentCWD string, dockerImage string, command []string, tty bool, settings *config.Settings, host hostSetup) (*RunSpec, error) {
+	containerENV, hostEnv, err := getENV(currentCWD, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	exposedPorts, portBindings, err := getPortBindings(settings.Ports)
+	if err != nil {
+		return nil, err
+	}
+
+	dockerContainerConfig := &container.Config{
+		Image:        dockerImage,
+		Cmd:          command,
+		Tty:          tty,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		WorkingDir:   "/app",
+		OpenStdin:    true,
+		StdinOnce:    !tty,
+		Env:          containerENV,
+		ExposedPorts: exposedPorts,
+		// Labels: ["cubx-container"]
+	}
+
+	mounts, binds, err := generateMounts(currentCWD, settings, host)
+	if err != nil {
+		return nil, fmt.Errorf("generate mounts error: %w", err)
+	}
+
+	dockerHostConfig := &container.HostConfig{
+		// NetworkMode:  container.NetworkMode("container:" + hostContainerId),
+		NetworkMode:  "host",
+		PortBindings: portBindings,
+		Mounts:       mounts,
+		Binds:        binds,
+	}
+
+	// Published ports are ignored in the host network
+	if len(portBindings) > 0 {
+		dockerHostConfig.NetworkMode = "bridge"
+	}
+
+	if settings.Init {
+		dockerHostConfig.Init = &settings.Init
+	}
+
+	if settings.Net != "" {
+		dockerHostConfig.NetworkMode = container.NetworkMode(settings.Net)
+	}
+
+	return &RunSpec{
+		Config:     dockerContainerConfig,
+		HostConfig: dockerHostConfig,
+		Platform:   settings.Platform,
+		HostEnv:    hostEnv,
+	}, nil
+}
+
+// MaskHostEnv replaces the values of the variables taken from the host
+// environment or an env file, which may be secrets, with ***.
+func (s *RunSpec) MaskHostEnv() {
+	for i, env := range s.Config.Env {
+		if key, _, _ := strings.Cut(env, "="); s.HostEnv[key] {
+			s.Config.Env[i] = key + "=***"
+		}
+	}
+}
+
+// DockerRunArgs returns the `docker run` command line that creates the same
+// container, quoted for a POSIX shell.
+func (s *RunSpec) DockerRunArgs() []string {
+	args := []string{"docker", "run", "--rm", "-i"}
+	if s.Config.Tty {
+		args = append(args, "-t")
+	}
+	if s.Platform != "" {
+		args = append(args, "--platform", s.Platform)
+	}
+	args = append(args, "--network", string(s.HostConfig.NetworkMode))
+	if s.HostConfig.Init != nil && *s.HostConfig.Init {
+		args = append(args, "--init")
+	}
+	args = append(args, "-w", s.Config.WorkingDir)
+
+	for _, env := range s.Config.Env {
+		args = append(args, "-e", env)
+	}
+
+	for _, m := range s.HostConfig.Mounts {
+		spec := "type=" + string(m.Type)
+		if m.Source != "" {
+			spec += ",source=" + m.Source
+		}
+		spec += ",target=" + m.Target
+		if m.ReadOnly {
+			spec += ",readonly"
+		}
+		if m.BindOptions != nil && m.BindOptions.Propagation != "" {
+			spec += ",bind-propagation=" + string(m.BindOptions.Propagation)
+		}
+		if m.VolumeOptions != nil {
+			for _, label := range sortedLabels(m.VolumeOptions.Labels) {
+				spec += ",volume-label=" + label
+			}
+		}
+		if m.TmpfsOptions != nil {
+			if m.TmpfsOptions.SizeBytes > 0 {
+				spec += fmt.Sprintf(",tmpfs-size=%d", m.TmpfsOptions.SizeBytes)
+			}
+			if m.TmpfsOptions.Mode != 0 {
+				spec += fmt.Sprintf(",tmpfs-mode=%o", m.TmpfsOptions.Mode)
+			}
+		}
+		args = append(args, "--mount", spec)
+	}
+	for _, bind := range s.HostConfig.Binds {
+		args = append(args, "-v", bind)
+	}
+
+	var ports []string
+	for port, bindings := range s.HostConfig.PortBindings {
+		for _, binding := range bindings {
+			hostPort := binding.HostPort
+			if binding.HostIP != "" {
+				hostPort = binding.HostIP + ":" + hostPort
+			}
+			ports = append(ports, fmt.Sprintf("%s:%s", hostPort, port))
+		}
+	}
+	sort.Strings(ports)
+	for _, port := range ports {
+		args = append(args, "-p", port)
+	}
+
+	args = append(args, s.Config.Image)
+	args = append(args, s.Config.Cmd...)
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return quoted
+}
+
+// sortedLabels formats labels as key=value in a stable order.
+func sortedLabels(labels map[string]string) []string {
+	var result []string
+	for key, value := range labels {
+		result = append(result, key+"="+value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// shellQuote quotes arg for a POSIX shell when it contains special characters.
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(arg, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
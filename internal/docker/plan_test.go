@@ -0,0 +1,30 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMaskHostEnv(t *testing.T) {
+	spec := &RunSpec{
+		Config: &container.Config{
+			Image:      "node",
+			WorkingDir: "/app",
+			Env:        []string{"CUBX_HOST_CWD=/work", "AWS_SECRET_ACCESS_KEY=secret", "NODE_ENV=test"},
+		},
+		HostConfig: &container.HostConfig{NetworkMode: "host"},
+		HostEnv:    map[string]bool{"AWS_SECRET_ACCESS_KEY": true},
+	}
+	spec.MaskHostEnv()
+
+	expected := []string{"CUBX_HOST_CWD=/work", "AWS_SECRET_ACCESS_KEY=***", "NODE_ENV=test"}
+	if diff := cmp.Diff(expected, spec.Config.Env); diff != "" {
+		t.Errorf("Unexpected env (-want +got):\n%s", diff)
+	}
+	if args := strings.Join(spec.DockerRunArgs(), " "); strings.Contains(args, "secret") {
+		t.Errorf("Expected the secret to be masked in %s", args)
+	}
+}
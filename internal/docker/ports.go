@@ -0,0 +1,22 @@
+package docker
+
+import (
+	"fmt"
+
+	"github.com/eddort/cubx/internal/config"
+
+	"github.com/docker/go-connections/nat"
+)
+
+// getPortBindings converts port specs in the host:container/proto syntax into
+// the exposed ports of the container and their host bindings.
+func getPortBindings(ports []string) (nat.PortSet, nat.PortMap, error) {
+	if err := config.ValidatePorts(ports); err != nil {
+		return nil, nil, fmt.Errorf("error publishing ports: %w", err)
+	}
+	exposedPorts, portBindings, err := nat.ParsePortSpecs(ports)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error publishing ports: %w", err)
+	}
+	return exposedPorts, portBindings, nil
+}
@@ -0,0 +1,43 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/eddort/cubx/internal/streams"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// resizeTTY sets the size of the container TTY to the size of the host terminal.
+func resizeTTY(ctx context.Context, cli *client.Client, containerID string, out *streams.Out) error {
+	height, width := out.GetTtySize()
+	if height == 0 && width == 0 {
+		return nil
+	}
+	return cli.ContainerResize(ctx, containerID, container.ResizeOptions{Height: height, Width: width})
+}
+
+// monitorTTYSize keeps the container TTY in sync with the host terminal by
+// resizing it on every SIGWINCH until ctx is done.
+func monitorTTYSize(ctx context.Context, cli *client.Client, containerID string, out *streams.Out) {
+	resizeTTY(ctx, cli, containerID, out)
+
+	sigwinch := make(chan os.Signal, 1)
+	signal.Notify(sigwinch, syscall.SIGWINCH)
+
+	go func() {
+		defer signal.Stop(sigwinch)
+		for {
+			select {
+			case <-sigwinch:
+				resizeTTY(ctx, cli, containerID, out)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -3,21 +3,22 @@ package docker
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/eddort/cubx/internal/config"
+	"github.com/eddort/cubx/internal/streams"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
 )
 
-func RunImageAndCommand(dockerImage string, command []string, config config.CLI, settings *config.Settings) error {
+// RunImageAndCommand runs the command in a new container created from dockerImage
+// and returns the exit status of the containerized process. The error is only set
+// when cubx or the Docker daemon failed, never because the program itself failed.
+func RunImageAndCommand(dockerImage string, command []string, ttyMode string, config config.CLI, settings *config.Settings) (int, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
-		return fmt.Errorf("error creating Docker client: %w", err)
+		return 0, fmt.Errorf("error creating Docker client: %w", err)
 	}
 
 	ctx := context.Background()
@@ -25,59 +26,41 @@ func RunImageAndCommand(dockerImage string, command []string, config config.CLI,
 	err = pullImage(ctx, cli, dockerImage, settings)
 
 	if err != nil {
-		return fmt.Errorf("error pulling a Docker container: %w", err)
+		return 0, fmt.Errorf("error pulling a Docker container: %w", err)
 	}
 
 	currentCWD, err := getCWD()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	containerENV := getENV(currentCWD)
+	in, out := streams.NewIn(), streams.NewOut()
+	tty := resolveTTY(ttyMode, in, out)
 
-	dockerContainerConfig := &container.Config{
-		Image:        dockerImage,
-		Cmd:          command,
-		Tty:          true,
-		AttachStdin:  true,
-		AttachStdout: true,
-		AttachStderr: true,
-		WorkingDir:   "/app",
-		OpenStdin:    true,
-		StdinOnce:    false,
-		Env:          containerENV,
-		// ExposedPorts: exposedPorts,
-		// Labels: ["cubx-container"]
-	}
-
-	mounts, err := generateMounts(currentCWD, settings.IgnorePaths, settings.Mounts)
+	spec, err := newRunSpec(currentCWD, dockerImage, command, tty, settings, runHost)
 	if err != nil {
-		return fmt.Errorf("generate mounts error: %w", err)
+		return 0, err
 	}
+	dockerContainerConfig, dockerHostConfig := spec.Config, spec.HostConfig
 
-	dockerHostConfig := &container.HostConfig{
-		// NetworkMode:  container.NetworkMode("container:" + hostContainerId),
-		NetworkMode: "host",
-		// TODO: portMappings from config
-		// PortBindings: portMappings,
-		Mounts: mounts,
-	}
-
-	if settings.Net != "" {
-		dockerHostConfig.NetworkMode = container.NetworkMode(settings.Net)
+	if tty {
+		height, width := out.GetTtySize()
+		dockerHostConfig.ConsoleSize = [2]uint{height, width}
 	}
 
 	resp, err := cli.ContainerCreate(ctx, dockerContainerConfig, dockerHostConfig, nil, nil, "")
 
 	if err != nil {
-		return fmt.Errorf("error creating a Docker container: %w", err)
+		return 0, fmt.Errorf("error creating a Docker container: %w", err)
 	}
 
-	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return cleanUpContainer(cli, ctx, resp.ID, fmt.Errorf("error starting a Docker container: %w", err))
-	}
+	// Relay signals to the program instead of tearing the container down. The
+	// forwarder is installed before the start, so that a signal received in
+	// the meantime does not kill cubx and leave the container behind.
+	forwarder := newSignalForwarder(cli, resp.ID, settings.StopTimeout)
+	defer forwarder.Stop()
 
-	out, err := cli.ContainerAttach(ctx, resp.ID, container.AttachOptions{
+	attach, err := cli.ContainerAttach(ctx, resp.ID, container.AttachOptions{
 		Stream: true,
 		Stdin:  true,
 		Stdout: true,
@@ -85,30 +68,55 @@ func RunImageAndCommand(dockerImage string, command []string, config config.CLI,
 		Logs:   true,
 	})
 	if err != nil {
-		return cleanUpContainer(cli, ctx, resp.ID, fmt.Errorf("error connecting to the container: %w", err))
+		return 0, cleanUpContainer(cli, ctx, resp.ID, fmt.Errorf("error connecting to the container: %w", err))
 	}
-	defer out.Close()
+	defer attach.Close()
 
-	go io.Copy(os.Stdout, out.Reader)
-	go io.Copy(out.Conn, os.Stdin)
+	statusCh, errCh := cli.ContainerWait(ctx, resp.ID, container.WaitConditionNextExit)
 
-	// Processing of termination signals
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	if tty {
+		restoreTerminal, err := setRawTerminal(in, out)
+		if err != nil {
+			return 0, cleanUpContainer(cli, ctx, resp.ID, err)
+		}
+		defer restoreTerminal()
+	}
 
-	statusCh, errCh := cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	outputDone := streamIO(attach, tty, in, out)
 
-	select {
-	case <-sigCh:
-		// fmt.Println("Completion signal received, stop and delete the container...")
-		return cleanUpContainer(cli, ctx, resp.ID, nil)
+	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return 0, cleanUpContainer(cli, ctx, resp.ID, fmt.Errorf("error starting a Docker container: %w", err))
+	}
 
-	case err := <-errCh:
-		return cleanUpContainer(cli, ctx, resp.ID, fmt.Errorf("error waiting for container completion: %w", err))
+	if tty {
+		resizeCtx, stopResize := context.WithCancel(ctx)
+		defer stopResize()
+		monitorTTYSize(resizeCtx, cli, resp.ID, out)
+	}
+
+	for {
+		select {
+		case sig := <-forwarder.signals:
+			if err := forwarder.handle(ctx, sig); err != nil {
+				return 0, cleanUpContainer(cli, ctx, resp.ID, err)
+			}
+
+		case <-forwarder.escalate:
+			if err := forwarder.kill(ctx); err != nil {
+				return 0, cleanUpContainer(cli, ctx, resp.ID, err)
+			}
 
-	case <-statusCh:
-		// fmt.Printf("The container has completed its work with the status %d\n", status.StatusCode)
-		return cleanUpContainer(cli, ctx, resp.ID, nil)
+		case err := <-errCh:
+			return 0, cleanUpContainer(cli, ctx, resp.ID, fmt.Errorf("error waiting for container completion: %w", err))
+
+		case status := <-statusCh:
+			if status.Error != nil {
+				return 0, cleanUpContainer(cli, ctx, resp.ID, fmt.Errorf("error waiting for container completion: %s", status.Error.Message))
+			}
+			// Drain the remaining output before the container is removed
+			<-outputDone
+			return int(status.StatusCode), cleanUpContainer(cli, ctx, resp.ID, nil)
+		}
 	}
 }
 
@@ -128,7 +136,7 @@ func cleanUpContainer(cli *client.Client, ctx context.Context, containerID strin
 		return wrapError(err, "failed to delete the container")
 	}
 
-	return nil
+	return customError
 }
 
 func getCWD() (string, error) {
@@ -146,15 +154,3 @@ func getCWD() (string, error) {
 	}
 	return ogCWD, nil
 }
-
-func getENV(currentCWD string) []string {
-	containerENVS := []string{}
-	termEnv := os.Getenv("TERM")
-	if termEnv != "" {
-		containerENVS = append(containerENVS, fmt.Sprintf("TERM=%s", termEnv))
-	}
-
-	containerENVS = append(containerENVS, fmt.Sprintf("CUBX_HOST_CWD=%s", currentCWD))
-	// TODO: pass env from .cubx/config
-	return containerENVS
-}
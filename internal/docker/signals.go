@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+// forwardedSignals maps the host signals relayed to the containerized process
+// to their names. Names are used instead of numbers because signal numbers
+// differ between the host OS and the Linux container.
+var forwardedSignals = map[os.Signal]string{
+	syscall.SIGINT:  "SIGINT",
+	syscall.SIGTERM: "SIGTERM",
+	syscall.SIGHUP:  "SIGHUP",
+	syscall.SIGQUIT: "SIGQUIT",
+	syscall.SIGUSR1: "SIGUSR1",
+	syscall.SIGUSR2: "SIGUSR2",
+}
+
+// signalForwarder relays host signals to a running container. A second
+// SIGINT, or a terminating signal that is not honoured within the stop
+// timeout, escalates to killing the container.
+type signalForwarder struct {
+	cli         *client.Client
+	containerID string
+	stopTimeout time.Duration
+	signals     chan os.Signal
+	escalate    <-chan time.Time
+	interrupted bool
+}
+
+func newSignalForwarder(cli *client.Client, containerID string, stopTimeout int) *signalForwarder {
+	f := &signalForwarder{
+		cli:         cli,
+		containerID: containerID,
+		stopTimeout: time.Duration(stopTimeout) * time.Second,
+		signals:     make(chan os.Signal, 1),
+	}
+	for sig := range forwardedSignals {
+		signal.Notify(f.signals, sig)
+	}
+	return f
+}
+
+// Stop stops relaying signals to the container.
+func (f *signalForwarder) Stop() {
+	signal.Stop(f.signals)
+}
+
+// handle forwards sig to the container or kills the container if the user
+// asked for it a second time.
+func (f *signalForwarder) handle(ctx context.Context, sig os.Signal) error {
+	if sig == syscall.SIGINT && f.interrupted {
+		return f.kill(ctx)
+	}
+
+	if err := f.cli.ContainerKill(ctx, f.containerID, forwardedSignals[sig]); err != nil {
+		if isNotRunning(err) {
+			return nil
+		}
+		return fmt.Errorf("error forwarding %s to the container: %w", forwardedSignals[sig], err)
+	}
+
+	if sig == syscall.SIGINT {
+		f.interrupted = true
+	}
+	if isTerminating(sig) && f.stopTimeout > 0 && f.escalate == nil {
+		f.escalate = time.After(f.stopTimeout)
+	}
+	return nil
+}
+
+// kill forcibly stops the container. A container that exited in the meantime
+// is not an error, its exit status is reported by the wait result.
+func (f *signalForwarder) kill(ctx context.Context) error {
+	fmt.Fprintln(os.Stderr, "cubx: killing the container")
+	if err := f.cli.ContainerKill(ctx, f.containerID, "SIGKILL"); err != nil && !isNotRunning(err) {
+		return fmt.Errorf("error killing the container: %w", err)
+	}
+	return nil
+}
+
+// isNotRunning reports whether a kill failed because the container already
+// exited or was removed.
+func isNotRunning(err error) bool {
+	return errdefs.IsNotFound(err) || errdefs.IsConflict(err)
+}
+
+func isTerminating(sig os.Signal) bool {
+	return sig == syscall.SIGINT || sig == syscall.SIGTERM || sig == syscall.SIGHUP || sig == syscall.SIGQUIT
+}
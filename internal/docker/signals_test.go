@@ -0,0 +1,25 @@
+package docker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/errdefs"
+)
+
+func TestIsNotRunning(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"exited":      {err: errdefs.Conflict(errors.New("Container abc is not running")), expected: true},
+		"removed":     {err: errdefs.NotFound(errors.New("No such container: abc")), expected: true},
+		"daemon":      {err: errdefs.System(errors.New("cannot kill container")), expected: false},
+		"unreachable": {err: errors.New("connection refused"), expected: false},
+	}
+	for name, test := range tests {
+		if got := isNotRunning(test.err); got != test.expected {
+			t.Errorf("%s: isNotRunning(%v) = %v, expected %v", name, test.err, got, test.expected)
+		}
+	}
+}
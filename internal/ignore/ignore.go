@@ -0,0 +1,343 @@
+// Package ignore matches paths against gitignore-style patterns.
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// FileName is the ignore file read from the project root.
+const FileName = ".cubxignore"
+
+// pattern is a single gitignore pattern relative to the base directory.
+type pattern struct {
+	base    string
+	negate  bool
+	dirOnly bool
+	// anchored patterns match relative to the base, others at any depth
+	anchored bool
+	// glob is the slash-separated pattern without the leading slash
+	glob string
+	re   *regexp.Regexp
+}
+
+// Matcher decides whether paths are ignored. Patterns added later take
+// precedence, so a negated pattern can re-include what an earlier one ignored.
+type Matcher struct {
+	patterns []pattern
+}
+
+// Path is a path found ignored by Matcher.Walk.
+type Path struct {
+	// Rel is the slash-separated path relative to the walked directory
+	Rel   string
+	IsDir bool
+}
+
+// Add adds patterns relative to the base directory. Blank lines and comments
+// are skipped. Absolute paths inside base are accepted and anchored to base.
+func (m *Matcher) Add(base string, lines ...string) error {
+	return m.add(base, false, lines)
+}
+
+// AddAnchored adds patterns that are all anchored to the base directory, like
+// paths relative to it. A leading **/ matches at any depth.
+func (m *Matcher) AddAnchored(base string, lines ...string) error {
+	return m.add(base, true, lines)
+}
+
+func (m *Matcher) add(base string, anchor bool, lines []string) error {
+	for _, line := range lines {
+		p, ok, err := parsePattern(base, line, anchor)
+		if err != nil {
+			return err
+		}
+		if ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+	return nil
+}
+
+// AddFile adds the patterns of an ignore file relative to its directory.
+// A missing file is not an error.
+func (m *Matcher) AddFile(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error opening ignore file: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading ignore file %s: %w", path, err)
+	}
+	return m.Add(filepath.Dir(path), lines...)
+}
+
+// Match reports whether the absolute path is ignored.
+func (m *Matcher) Match(path string, isDir bool) bool {
+	ignored := false
+	for _, p := range m.patterns {
+		rel, err := filepath.Rel(p.base, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(filepath.ToSlash(rel)) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// Walk returns the ignored files and directories under root. The contents of
+// an ignored directory are not visited, as in git a file cannot be re-included
+// when its parent directory is ignored. Literal paths are checked directly,
+// otherwise only the directories that a pattern can match below are visited.
+func (m *Matcher) Walk(root string) ([]Path, error) {
+	var paths []Path
+	if len(m.patterns) == 0 {
+		return paths, nil
+	}
+	if m.literal() {
+		return m.statLiterals(root)
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories cannot be mounted into the container either
+			if path != root && os.IsPermission(err) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if m.Match(path, d.IsDir()) {
+			paths = append(paths, Path{Rel: filepath.ToSlash(rel), IsDir: d.IsDir()})
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		// Nothing is ever mounted from inside the repository metadata
+		if d.IsDir() && (d.Name() == ".git" || !m.MayMatchBelow(path)) {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking %s: %w", root, err)
+	}
+	return paths, nil
+}
+
+// literal reports whether every pattern is an anchored path without globs.
+func (m *Matcher) literal() bool {
+	for _, p := range m.patterns {
+		if !p.anchored || strings.ContainsAny(p.glob, `*?[\`) {
+			return false
+		}
+	}
+	return true
+}
+
+// statLiterals resolves literal patterns without walking root.
+func (m *Matcher) statLiterals(root string) ([]Path, error) {
+	seen := make(map[string]bool)
+	var paths []Path
+	for _, p := range m.patterns {
+		if p.negate {
+			continue
+		}
+		path := filepath.Join(p.base, filepath.FromSlash(p.glob))
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || seen[rel] {
+			continue
+		}
+		seen[rel] = true
+
+		info, err := os.Lstat(path)
+		if err != nil {
+			if os.IsNotExist(err) || os.IsPermission(err) || errors.Is(err, syscall.ENOTDIR) {
+				continue
+			}
+			return nil, fmt.Errorf("error checking %s: %w", path, err)
+		}
+		if !m.Match(path, info.IsDir()) || m.parentMatched(root, path) {
+			continue
+		}
+		paths = append(paths, Path{Rel: filepath.ToSlash(rel), IsDir: info.IsDir()})
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i].Rel < paths[j].Rel })
+	return paths, nil
+}
+
+// parentMatched reports whether a directory between root and path is ignored,
+// which hides path already.
+func (m *Matcher) parentMatched(root, path string) bool {
+	for dir := filepath.Dir(path); dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if m.Match(dir, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// MayMatchBelow reports whether a pattern can match a path inside dir.
+// Negated patterns are not considered since they never ignore anything.
+func (m *Matcher) MayMatchBelow(dir string) bool {
+	for _, p := range m.patterns {
+		if p.negate {
+			continue
+		}
+		rel, err := filepath.Rel(p.base, dir)
+		if err != nil {
+			return true
+		}
+		if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			// The pattern applies below its base, which may lie inside dir
+			if strings.HasPrefix(p.base, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+				return true
+			}
+			continue
+		}
+		if rel == "." || !p.anchored || p.mayMatchBelow(filepath.ToSlash(rel)) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayMatchBelow reports whether the anchored pattern can match a path inside
+// the directory rel, relative to the base of the pattern.
+func (p pattern) mayMatchBelow(rel string) bool {
+	segments := strings.Split(p.glob, "/")
+	for i, dir := range strings.Split(rel, "/") {
+		if i >= len(segments) {
+			return false
+		}
+		if strings.Contains(segments[i], "**") {
+			return true
+		}
+		re, err := regexp.Compile("^" + translate(segments[i]) + "$")
+		if err != nil {
+			return true
+		}
+		if !re.MatchString(dir) {
+			return false
+		}
+	}
+	return len(segments) > len(strings.Split(rel, "/"))
+}
+
+func parsePattern(base, line string, anchor bool) (pattern, bool, error) {
+	line = strings.TrimRight(line, " \t")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false, nil
+	}
+
+	p := pattern{base: base}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	// Paths of the host such as --ignore-path $PWD/.env
+	if filepath.IsAbs(line) && strings.HasPrefix(line, base+string(filepath.Separator)) {
+		line = "/" + filepath.ToSlash(strings.TrimPrefix(line, base+string(filepath.Separator)))
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	line = strings.TrimPrefix(line, "./")
+
+	// A pattern with a slash other than at the end is relative to the base,
+	// otherwise it matches at any depth
+	anchored := anchor || strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return pattern{}, false, nil
+	}
+	// A leading **/ matches at any depth, the same as an unanchored pattern
+	if rest := strings.TrimPrefix(line, "**/"); rest != line && !strings.Contains(rest, "/") {
+		anchored, line = false, rest
+	}
+	p.anchored, p.glob = anchored, line
+
+	expr := translate(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return pattern{}, false, fmt.Errorf("invalid ignore pattern %q: %w", line, err)
+	}
+	p.re = re
+	return p, true, nil
+}
+
+// translate converts a gitignore glob into a regular expression.
+func translate(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**") && i+2 == len(glob):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,200 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMatch(t *testing.T) {
+	base := "/project"
+	matcher := &Matcher{}
+	err := matcher.Add(base,
+		"# secrets",
+		".env",
+		"**/*.pem",
+		"!public.pem",
+		"/build/",
+		"config/*.local",
+		"logs/**",
+		`\#notes`,
+		"/project/abs.txt",
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{path: ".env", expected: true},
+		{path: "config/.env", expected: true},
+		{path: ".env.example"},
+		{path: "certs/key.pem", expected: true},
+		{path: "key.pem", expected: true},
+		{path: "certs/public.pem"},
+		{path: "build", isDir: true, expected: true},
+		{path: "build"},
+		{path: "src/build", isDir: true},
+		{path: "config/app.local", expected: true},
+		{path: "src/config/app.local"},
+		{path: "config/nested/app.local"},
+		{path: "logs/a/b.log", expected: true},
+		{path: "#notes", expected: true},
+		{path: "abs.txt", expected: true},
+	}
+
+	for _, test := range tests {
+		if matched := matcher.Match(filepath.Join(base, test.path), test.isDir); matched != test.expected {
+			t.Errorf("Match(%s, %v) = %v, expected %v", test.path, test.isDir, matched, test.expected)
+		}
+	}
+
+	if matcher.Match("/elsewhere/.env", false) {
+		t.Errorf("Expected paths outside of the base to be kept")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{
+		".env",
+		"config/.env",
+		"certs/key.pem",
+		"certs/public.pem",
+		"node_modules/pkg/index.js",
+		"src/main.go",
+	} {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, FileName), []byte("*.pem\n!public.pem\nnode_modules/\n"), 0644); err != nil {
+		t.Fatalf("Failed to write ignore file: %v", err)
+	}
+
+	matcher := &Matcher{}
+	if err := matcher.AddFile(filepath.Join(root, FileName)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := matcher.Add(root, ".env", "missing/**"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	paths, err := matcher.Walk(root)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []Path{
+		{Rel: ".env"},
+		{Rel: "certs/key.pem"},
+		{Rel: "config/.env"},
+		{Rel: "node_modules", IsDir: true},
+	}
+	if diff := cmp.Diff(expected, paths); diff != "" {
+		t.Errorf("Unexpected ignored paths (-want +got):\n%s", diff)
+	}
+
+	if err := (&Matcher{}).AddFile(filepath.Join(root, "missing", FileName)); err != nil {
+		t.Errorf("Expected a missing ignore file to be skipped, got %v", err)
+	}
+}
+
+func TestAddAnchored(t *testing.T) {
+	base := "/project"
+	matcher := &Matcher{}
+	if err := matcher.AddAnchored(base, ".env", "*.pem", "**/secrets.json", "/project/abs.txt"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := map[string]bool{
+		".env":                  true,
+		"config/.env":           false,
+		"key.pem":               true,
+		"certs/key.pem":         false,
+		"secrets.json":          true,
+		"config/a/secrets.json": true,
+		"abs.txt":               true,
+	}
+	for path, expected := range tests {
+		if matched := matcher.Match(filepath.Join(base, path), false); matched != expected {
+			t.Errorf("Match(%s) = %v, expected %v", path, matched, expected)
+		}
+	}
+}
+
+func TestWalkLiteral(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{".env", "config/.env", "config/app.yaml", "build/out.js"} {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+
+	matcher := &Matcher{}
+	if err := matcher.AddAnchored(root, ".env", "config", "config/.env", "build/", "missing", "!config/app.yaml", ".env/nested"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !matcher.literal() {
+		t.Fatalf("Expected literal patterns to be resolved without walking")
+	}
+
+	paths, err := matcher.Walk(root)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []Path{
+		{Rel: ".env"},
+		{Rel: "build", IsDir: true},
+		{Rel: "config", IsDir: true},
+	}
+	if diff := cmp.Diff(expected, paths); diff != "" {
+		t.Errorf("Unexpected ignored paths (-want +got):\n%s", diff)
+	}
+}
+
+func TestMayMatchBelow(t *testing.T) {
+	base := "/project"
+	matcher := &Matcher{}
+	if err := matcher.AddAnchored(base, "packages/*/dist", "docs/**/draft.md", "!node_modules/keep"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := map[string]bool{
+		"packages":          true,
+		"packages/app":      true,
+		"packages/app/src":  false,
+		"docs":              true,
+		"docs/guide/nested": true,
+		"node_modules":      false,
+		"src":               false,
+	}
+	for dir, expected := range tests {
+		if got := matcher.MayMatchBelow(filepath.Join(base, dir)); got != expected {
+			t.Errorf("MayMatchBelow(%s) = %v, expected %v", dir, got, expected)
+		}
+	}
+	if !matcher.MayMatchBelow("/") {
+		t.Errorf("Expected the parent of the base to be visited")
+	}
+
+	unanchored := &Matcher{}
+	if err := unanchored.Add(base, ".env"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !unanchored.MayMatchBelow(filepath.Join(base, "src")) {
+		t.Errorf("Expected unanchored patterns to match at any depth")
+	}
+}
@@ -3,6 +3,7 @@ package platform
 import (
 	"fmt"
 	"github.com/eddort/cubx/internal/registry"
+	"sort"
 )
 
 type OsArch struct {
@@ -52,6 +53,16 @@ func IsValidOsArch(Os string, Arch string) bool {
 	return ok
 }
 
+// ValidPlatforms returns the supported platforms in the os/arch form, sorted.
+func ValidPlatforms() []string {
+	var platforms []string
+	for osArch := range validOsArches {
+		platforms = append(platforms, osArch.Os+"/"+osArch.Arch)
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
 func GetPlatforms(imageName string) (*PlatformMap, error) {
 	manifests, err := registry.FetchManifests(imageName)
 	if err != nil {
@@ -11,23 +11,29 @@ import (
 
 func main() {
 
-	configuration, _, err := config.LoadConfig(true)
-
-	if err != nil {
-		tui.PrintError(err)
-		os.Exit(1)
+	configuration, _, loadErr := config.LoadConfig(true)
+	if loadErr != nil {
+		configuration = &config.ProgramConfig{}
 	}
 
 	commandArgs, flags := cli.Parse(*configuration)
 
-	err = command.Execute(commandArgs, flags, configuration)
+	// The config subcommands report problems of the config files themselves
+	if loadErr != nil && !command.IsConfigCommand(commandArgs) {
+		tui.PrintError(loadErr)
+		os.Exit(command.ExitCodeFailure)
+	}
+
+	err := command.Execute(commandArgs, flags, configuration)
 	if err != nil {
 		if errors.Is(err, command.ErrCommandNotFound) {
 			cli.ShowHelpMessage(*configuration)
 			os.Exit(0)
-		} else {
+		}
+		var exitErr *command.ExitError
+		if !errors.As(err, &exitErr) {
 			tui.PrintError(err)
-			os.Exit(1)
 		}
+		os.Exit(command.ExitCode(err))
 	}
 }